@@ -1,6 +1,7 @@
 package accessor
 
 import (
+    "math"
     "math/rand"
     "sort"
     "sync"
@@ -15,6 +16,7 @@ type Song struct {
     Artist   string
     URL      string
     Duration time.Duration
+    Tags     []string `json:",omitempty"` // normalized, sorted; see NormalizeTag
 }
 
 type Playlist struct {
@@ -24,17 +26,36 @@ type Playlist struct {
     lastPlayed        map[string]time.Time
     lastRadioPlayed   map[string]time.Time
     rng               *rand.Rand
-    cooldown          time.Duration
-    maxChance         float64
-    lastRandom        time.Time
     NewSongCh         chan struct{}
+    PlanChangedCh     chan struct{} // see Replan
     forceNextRadio    bool
 
+    // when radio segments play; see SegmentRule
+    segmentRules      []*SegmentRule
+    lastWasSegment    bool
+
+    // undo state for the most recent Next; see Requeue
+    pick              *lastPick
+    prevPlayed        time.Time
+
     // these hold the current “deck” for each list
     shuffledQueue     []Song
     shuffledIndex     int
     shuffledRadio     []Song
     shuffledRadioIndex int
+
+    // listener ratings bias the master shuffle
+    scorer            func(songID string) int
+    ratingBias        float64
+
+    // most recent removal, restorable until undoWindow passes
+    lastRemoval       *Removal
+    undoWindow        time.Duration
+
+    // programming blocks narrow the master pool during their windows
+    schedule          *Schedule
+    // station-wide tag filter on the master rotation; nil plays everything
+    rotation          *TagExpr
 }
 
 func NewPlaylist(cfg *config.Config) *Playlist {
@@ -43,19 +64,22 @@ func NewPlaylist(cfg *config.Config) *Playlist {
         lastPlayed:      make(map[string]time.Time),
         lastRadioPlayed: make(map[string]time.Time),
         rng:             rand.New(src),
-        cooldown:        cfg.RandomCooldown,
-        maxChance:       cfg.RandomMaxChance,
+        segmentRules:    defaultSegmentRules(cfg.RandomCooldown, cfg.RandomMaxChance),
         NewSongCh:       make(chan struct{}, 1),
+        PlanChangedCh:   make(chan struct{}, 1),
+        undoWindow:      cfg.UndoWindow,
+        ratingBias:      cfg.RatingBias,
     }
 }
 
-func (p *Playlist) Add(song Song) {
+// Add appends song to the master list; false means it was already there.
+func (p *Playlist) Add(song Song) bool {
     p.mu.Lock()
     defer p.mu.Unlock()
     // de-dupe
     for _, s := range p.queue {
         if s.ID == song.ID {
-            return
+            return false
         }
     }
     first := len(p.queue) == 0
@@ -63,14 +87,16 @@ func (p *Playlist) Add(song Song) {
     if first {
         select { case p.NewSongCh <- struct{}{}: default: }
     }
+    return true
 }
 
-func (p *Playlist) AddRadio(song Song) {
+// AddRadio appends song to the radio‐segment list; false means it was already there.
+func (p *Playlist) AddRadio(song Song) bool {
     p.mu.Lock()
     defer p.mu.Unlock()
     for _, s := range p.randomNext {
         if s.ID == song.ID {
-            return
+            return false
         }
     }
     first := len(p.randomNext) == 0
@@ -78,60 +104,128 @@ func (p *Playlist) AddRadio(song Song) {
     if first {
         select { case p.NewSongCh <- struct{}{}: default: }
     }
+    return true
 }
 
+// Remove drops id from both the master and radio lists (undoable).
 func (p *Playlist) Remove(id string) {
-    p.mu.Lock()
-    defer p.mu.Unlock()
-    // queue
-    q := p.queue[:0]
-    for _, s := range p.queue {
-        if s.ID != id {
-            q = append(q, s)
-        }
-    }
-    p.queue = q
-    delete(p.lastPlayed, id)
-
-    // radio
-    r := p.randomNext[:0]
-    for _, s := range p.randomNext {
-        if s.ID != id {
-            r = append(r, s)
-        }
-    }
-    p.randomNext = r
-    delete(p.lastRadioPlayed, id)
+    p.removeWhere(func(s Song) bool { return s.ID == id }, true, true, id)
 }
 
-// Next gives you the next track: forced radio, cooldown bump, or weighted master.
+// Next gives you the next track: forced radio, a due segment rule, or weighted master.
 func (p *Playlist) Next() (Song, bool) {
     p.mu.Lock()
     defer p.mu.Unlock()
 
     now := time.Now()
+    block, inBlock := p.schedule.Active(now)
+    var cooldown time.Duration
+    if inBlock {
+        cooldown = block.RadioCooldown()
+    }
+    lp := p.rememberPick()
+    p.pick = nil
 
     // 0) forced radio segment
     if p.forceNextRadio && len(p.randomNext) > 0 {
         song, _ := p.popShuffledRadio(now)
         p.forceNextRadio = false
-        p.lastRandom = now
+        p.lastWasSegment = true
+        p.finishPick(lp, song, true)
         return song, true
     }
-    // 1) cooldown‐based radio bump
-    if now.Sub(p.lastRandom) >= p.cooldown && len(p.randomNext) > 0 {
-        song, _ := p.popShuffledRadio(now)
-        p.lastRandom = now
+    // 1) segment rules: top of hour, every N songs, cooldown ramp
+    if song, ok := p.nextSegment(now, cooldown); ok {
+        p.lastWasSegment = true
+        p.finishPick(lp, song, true)
         return song, true
     }
     // 2) weighted master queue
     if len(p.queue) == 0 {
         return Song{}, false
     }
-    song, ok := p.popShuffledQueue(now)
+    // an on-air block replaces the rotation filter; if nothing qualifies we
+    // fall back to the whole list rather than go silent
+    var filter func(Song) bool
+    switch {
+    case inBlock:
+        filter = block.Selects
+    case p.rotation != nil:
+        filter = p.rotation.Matches
+    }
+    song, ok := Song{}, false
+    if filter != nil {
+        song, ok = p.popFiltered(now, filter)
+    }
+    if !ok {
+        song, ok = p.popShuffledQueue(now)
+    }
+    if ok {
+        p.lastWasSegment = false
+        p.countSong()
+        p.finishPick(lp, song, false)
+    }
     return song, ok
 }
 
+// popFiltered pops the next song in the deck that match accepts. When the
+// rest of the deck has none but the master list does, the matching songs
+// have all played this round, so it deals a fresh deck and tries again;
+// false means nothing in the master list qualifies.
+func (p *Playlist) popFiltered(now time.Time, match func(Song) bool) (Song, bool) {
+    if p.shuffledQueue == nil || p.shuffledIndex >= len(p.shuffledQueue) {
+        p.refillShuffledQueue(now)
+    }
+    if song, ok := p.popMatch(now, match); ok {
+        return song, true
+    }
+    var cands []Song
+    for _, s := range p.queue {
+        if match(s) {
+            cands = append(cands, s)
+        }
+    }
+    if len(cands) == 0 {
+        return Song{}, false
+    }
+    p.refillShuffledQueue(now)
+    if song, ok := p.popMatch(now, match); ok {
+        return song, true
+    }
+    // the deal left out every matching song; deal one in by weight
+    p.shuffledQueue = append(p.shuffledQueue, p.weightedPick(cands))
+    return p.popMatch(now, match)
+}
+
+// weightedPick chooses one of songs with odds in proportion to ratingWeight.
+func (p *Playlist) weightedPick(songs []Song) Song {
+    total := 0.0
+    for _, s := range songs {
+        total += p.ratingWeight(s.ID)
+    }
+    r := p.rng.Float64() * total
+    for _, s := range songs {
+        if r -= p.ratingWeight(s.ID); r < 0 {
+            return s
+        }
+    }
+    return songs[len(songs)-1]
+}
+
+// popMatch pops the first song in the rest of the deck that match accepts.
+func (p *Playlist) popMatch(now time.Time, match func(Song) bool) (Song, bool) {
+    for i := p.shuffledIndex; i < len(p.shuffledQueue); i++ {
+        if !match(p.shuffledQueue[i]) {
+            continue
+        }
+        // move the pick to the front so the skipped songs stay in the deck
+        q := p.shuffledQueue
+        q[p.shuffledIndex], q[i] = q[i], q[p.shuffledIndex]
+        return p.popShuffledQueue(now)
+    }
+    return Song{}, false
+}
+
 // popShuffledQueue refills and sorts the master deck if needed, then pops one.
 func (p *Playlist) popShuffledQueue(now time.Time) (Song, bool) {
     if p.shuffledQueue == nil || p.shuffledIndex >= len(p.shuffledQueue) {
@@ -142,26 +236,38 @@ func (p *Playlist) popShuffledQueue(now time.Time) (Song, bool) {
     }
     s := p.shuffledQueue[p.shuffledIndex]
     p.shuffledIndex++
+    p.prevPlayed = p.lastPlayed[s.ID]
     p.lastPlayed[s.ID] = now
     return s, true
 }
 
-// refillShuffledQueue does a weighted shuffle on queue by age*rand.
+// refillShuffledQueue deals a new deck: each song makes it in with odds of
+// its rating weight over the best one's, so ratings set how often a song
+// plays, and the deck is shuffled by age*rand so long-unplayed songs come first.
 func (p *Playlist) refillShuffledQueue(now time.Time) {
-    n := len(p.queue)
     type entry struct {
         song Song
         key  float64
     }
-    ents := make([]entry, n)
+    weights := make([]float64, len(p.queue))
+    maxW := 0.0
+    for i, s := range p.queue {
+        weights[i] = p.ratingWeight(s.ID)
+        maxW = math.Max(maxW, weights[i])
+    }
+    ents := make([]entry, 0, len(p.queue))
     total := 0.0
     for i, s := range p.queue {
+        // the best-rated songs always make it, since Float64 < 1
+        if p.rng.Float64() >= weights[i]/maxW {
+            continue
+        }
         age := now.Sub(p.lastPlayed[s.ID]).Seconds()
         if age < 1 {
             age = 1
         }
         k := age * p.rng.Float64()
-        ents[i] = entry{s, k}
+        ents = append(ents, entry{s, k})
         total += k
     }
     // fallback if all keys zero
@@ -173,13 +279,37 @@ func (p *Playlist) refillShuffledQueue(now time.Time) {
     sort.Slice(ents, func(i, j int) bool {
         return ents[i].key > ents[j].key
     })
-    p.shuffledQueue = make([]Song, n)
+    p.shuffledQueue = make([]Song, len(ents))
     for i, e := range ents {
         p.shuffledQueue[i] = e.song
     }
     p.shuffledIndex = 0
 }
 
+// SetScorer installs the rating lookup that sets how often master-list songs play.
+func (p *Playlist) SetScorer(fn func(songID string) int) {
+    p.mu.Lock()
+    p.scorer = fn
+    p.mu.Unlock()
+}
+
+// SetSchedule installs the programming blocks Next consults.
+func (p *Playlist) SetSchedule(s *Schedule) {
+    p.mu.Lock()
+    p.schedule = s
+    p.mu.Unlock()
+}
+
+// ratingWeight is a song's relative play frequency, (1+ratingBias)^score,
+// clamped to [1/8, 8] so one very popular track can't take over.
+func (p *Playlist) ratingWeight(id string) float64 {
+    if p.scorer == nil || p.ratingBias <= 0 {
+        return 1
+    }
+    w := math.Pow(1+p.ratingBias, float64(p.scorer(id)))
+    return math.Max(0.125, math.Min(8, w))
+}
+
 // popShuffledRadio refills/sorts the radio deck, then pops one.
 func (p *Playlist) popShuffledRadio(now time.Time) (Song, bool) {
     if p.shuffledRadio == nil || p.shuffledRadioIndex >= len(p.shuffledRadio) {
@@ -190,6 +320,7 @@ func (p *Playlist) popShuffledRadio(now time.Time) (Song, bool) {
     }
     s := p.shuffledRadio[p.shuffledRadioIndex]
     p.shuffledRadioIndex++
+    p.prevPlayed = p.lastRadioPlayed[s.ID]
     p.lastRadioPlayed[s.ID] = now
     return s, true
 }
@@ -227,9 +358,11 @@ func (p *Playlist) refillShuffledRadio(now time.Time) {
     p.shuffledRadioIndex = 0
 }
 
-// ForceNextRadioSegment makes the very next Next() call use randomNext.
+// ForceNextRadioSegment makes the very next Next() call use randomNext
+// and asks the broadcaster to replan, so the segment follows the current track.
 func (p *Playlist) ForceNextRadioSegment() {
     p.mu.Lock()
     p.forceNextRadio = true
     p.mu.Unlock()
+    p.Replan()
 }
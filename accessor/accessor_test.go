@@ -0,0 +1,117 @@
+package accessor
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/Coop25/CC-Radio/config"
+)
+
+// newTestPlaylist returns a playlist of n master-list songs s0…s(n-1).
+func newTestPlaylist(t *testing.T, n int) *Playlist {
+	t.Helper()
+	p := NewPlaylist(&config.Config{UndoWindow: time.Minute})
+	sched, err := NewSchedule("UTC", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.SetSchedule(sched)
+	for i := 0; i < n; i++ {
+		p.Add(Song{ID: fmt.Sprintf("s%d", i), Name: fmt.Sprintf("Song %d", i)})
+	}
+	return p
+}
+
+// onAirBlock is a daily block running from an hour ago to an hour from now.
+func onAirBlock(songs ...string) Block {
+	now := time.Now().UTC()
+	return Block{
+		Name:  "test",
+		Days:  "daily",
+		Start: now.Add(-time.Hour).Format("15:04"),
+		End:   now.Add(time.Hour).Format("15:04"),
+		Songs: songs,
+	}
+}
+
+func TestBlockAcrossDeckBoundary(t *testing.T) {
+	p := newTestPlaylist(t, 10)
+	if err := p.schedule.Put(onAirBlock("s3", "s7")); err != nil {
+		t.Fatal(err)
+	}
+	// three decks' worth of picks: the block must hold past the point where
+	// the rest of a deck has none of its songs left
+	for i := 0; i < 30; i++ {
+		s, ok := p.Next()
+		if !ok {
+			t.Fatalf("pick %d: nothing to play", i)
+		}
+		if s.ID != "s3" && s.ID != "s7" {
+			t.Fatalf("pick %d: %s played during a block of s3 and s7", i, s.ID)
+		}
+	}
+}
+
+func TestBlockWithNoMatchFallsBack(t *testing.T) {
+	p := newTestPlaylist(t, 5)
+	if err := p.schedule.Put(onAirBlock("gone")); err != nil {
+		t.Fatal(err)
+	}
+	seen := make(map[string]bool)
+	for i := 0; i < 5; i++ {
+		s, ok := p.Next()
+		if !ok {
+			t.Fatalf("pick %d: nothing to play", i)
+		}
+		seen[s.ID] = true
+	}
+	if len(seen) != 5 {
+		t.Errorf("fallback played %d distinct songs in one deck, want 5", len(seen))
+	}
+}
+
+// playCounts picks n songs and counts each.
+func playCounts(t *testing.T, p *Playlist, n int) map[string]int {
+	t.Helper()
+	counts := make(map[string]int)
+	for i := 0; i < n; i++ {
+		s, ok := p.Next()
+		if !ok {
+			t.Fatalf("pick %d: nothing to play", i)
+		}
+		counts[s.ID]++
+	}
+	return counts
+}
+
+func TestRatingsSetPlayFrequency(t *testing.T) {
+	p := newTestPlaylist(t, 4)
+	r := NewRatings()
+	for u := 0; u < 5; u++ {
+		r.Rate("s0", fmt.Sprint("fan", u), 1)
+		r.Rate("s1", fmt.Sprint("critic", u), -1)
+	}
+	r.ToggleFavorite("fan0", "s0")
+	if got := r.PlayScore("s0"); got != 6 {
+		t.Fatalf("PlayScore with 5 likes and a favorite = %d, want 6", got)
+	}
+	p.SetScorer(r.PlayScore)
+
+	// unbiased, every deck holds every song once
+	even := playCounts(t, p, 4000)
+	for id, n := range even {
+		if n != 1000 {
+			t.Errorf("without a rating bias %s played %d times, want 1000", id, n)
+		}
+	}
+
+	p.ratingBias = 0.5
+	got := playCounts(t, p, 4000)
+	if got["s0"] < 4*got["s2"] {
+		t.Errorf("liked s0 played %d times vs %d for unrated s2; want it far more often", got["s0"], got["s2"])
+	}
+	if 3*got["s1"] > got["s2"] {
+		t.Errorf("disliked s1 played %d times vs %d for unrated s2; want it far less often", got["s1"], got["s2"])
+	}
+}
@@ -0,0 +1,193 @@
+package accessor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Request kinds an endpoint can be preferred for.
+const (
+	KindSearch = "search" // song and playlist lookups
+	KindAudio  = "audio"  // audio downloads
+)
+
+// BackendConfig is one converter endpoint in FETCH_BACKENDS.
+type BackendConfig struct {
+	URL    string `json:"url"`
+	Weight int    `json:"weight,omitempty"` // share of round-robin picks; default 1
+	Prefer string `json:"prefer,omitempty"` // "search", "audio" or empty for either
+}
+
+// parseBackends reads the FETCH_BACKENDS JSON list, falling back to a
+// single endpoint at baseURL when it is empty.
+func parseBackends(raw, baseURL string) ([]BackendConfig, error) {
+	if raw == "" {
+		if baseURL == "" {
+			return nil, fmt.Errorf("set FETCH_BASE_URL or FETCH_BACKENDS")
+		}
+		return []BackendConfig{{URL: baseURL, Weight: 1}}, nil
+	}
+	var out []BackendConfig
+	if err := json.Unmarshal([]byte(raw), &out); err != nil {
+		return nil, fmt.Errorf("invalid FETCH_BACKENDS: %w", err)
+	}
+	if len(out) == 0 {
+		return nil, fmt.Errorf("FETCH_BACKENDS lists no endpoints")
+	}
+	for i := range out {
+		switch {
+		case out[i].URL == "":
+			return nil, fmt.Errorf("FETCH_BACKENDS entry %d has no url", i+1)
+		case out[i].Prefer != "" && out[i].Prefer != KindSearch && out[i].Prefer != KindAudio:
+			return nil, fmt.Errorf("FETCH_BACKENDS entry %d: prefer must be %q or %q", i+1, KindSearch, KindAudio)
+		}
+		if out[i].Weight < 1 {
+			out[i].Weight = 1
+		}
+	}
+	return out, nil
+}
+
+// backend is one converter endpoint with its own breaker.
+type backend struct {
+	BackendConfig
+	breaker *breaker
+	current int // smooth weighted round-robin credit, guarded by pool.mu
+}
+
+// rank orders endpoints for a request of kind: preferred first, then
+// neutral ones, then those preferred for the other kind.
+func (be *backend) rank(kind string) int {
+	switch be.Prefer {
+	case kind:
+		return 0
+	case "":
+		return 1
+	}
+	return 2
+}
+
+// pool spreads requests over the converter endpoints.
+type pool struct {
+	mu       sync.Mutex
+	backends []*backend
+}
+
+// pick chooses the next endpoint for kind by smooth weighted round-robin
+// among the best-ranked ones that are available, skipping those in skip
+// and those behind an open breaker. It returns nil when none is left.
+func (p *pool) pick(kind string, skip map[*backend]bool) *backend {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	passed := make(map[*backend]bool)
+	for rank := 0; rank < 3; rank++ {
+		for {
+			var cands []*backend
+			total := 0
+			for _, be := range p.backends {
+				if be.rank(kind) == rank && !skip[be] && !passed[be] && be.breaker.available() {
+					cands = append(cands, be)
+					total += be.Weight
+				}
+			}
+			if len(cands) == 0 {
+				break
+			}
+			var best *backend
+			for _, be := range cands {
+				be.current += be.Weight
+				if best == nil || be.current > best.current {
+					best = be
+				}
+			}
+			best.current -= total
+			if best.breaker.allow() == nil {
+				return best
+			}
+			passed[best] = true // another caller took its probe slot
+		}
+	}
+	return nil
+}
+
+// downError explains why no endpoint could take a request.
+func (p *pool) downError() error {
+	var soonest time.Time
+	for _, be := range p.backends {
+		if at := be.breaker.health().RetryAt; !at.IsZero() && (soonest.IsZero() || at.Before(soonest)) {
+			soonest = at
+		}
+	}
+	if wait := time.Until(soonest); !soonest.IsZero() && wait > 0 {
+		return fmt.Errorf("%w; retrying in %s", ErrConverterDown, wait.Round(time.Second))
+	}
+	return fmt.Errorf("%w; checking whether it is back", ErrConverterDown)
+}
+
+// health reports every endpoint, and the converter as up while any one is.
+func (p *pool) health() ConverterHealth {
+	out := ConverterHealth{State: ConverterDown}
+	for _, be := range p.backends {
+		bh := be.breaker.health()
+		bh.URL, bh.Weight, bh.Prefer = be.URL, be.Weight, be.Prefer
+		out.Backends = append(out.Backends, bh)
+		switch {
+		case bh.State == ConverterUp:
+			out.State = ConverterUp
+		case bh.State == ConverterProbing && out.State == ConverterDown:
+			out.State = ConverterProbing
+		}
+	}
+	return out
+}
+
+// CheckHealth probes every endpoint each interval, so a dead one leaves
+// the rotation (and a recovered one rejoins it) without a listener's
+// request having to find out. It returns when ctx is done.
+func (h *httpFetcher) CheckHealth(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			for _, be := range h.pool.backends {
+				h.probe(ctx, be)
+			}
+		}
+	}
+}
+
+// probe asks be for its index page; any answer short of a server error or
+// a refusal means it is alive.
+func (h *httpFetcher) probe(ctx context.Context, be *backend) {
+	ctx, cancel := context.WithTimeout(ctx, h.audioTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, "GET", be.URL+"?v=2", nil)
+	if err != nil {
+		return
+	}
+	req.Header = h.headers
+	resp, err := h.client.Do(req)
+	if err == nil {
+		resp.Body.Close()
+		err = &statusError{code: resp.StatusCode, status: resp.Status}
+		if resp.StatusCode < 500 && !refused(err) {
+			err = nil
+		}
+	}
+	if err != nil {
+		h.log.Debug("health check failed", "backend", be.URL, "err", err)
+		be.breaker.failure(err)
+		return
+	}
+	be.breaker.success()
+}
@@ -0,0 +1,248 @@
+package accessor
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Coop25/CC-Radio/config"
+)
+
+// testBackend is a converter endpoint that answers searches with "[]" and
+// audio with a few bytes, or with status/delay when they are set.
+type testBackend struct {
+	*httptest.Server
+	status   atomic.Int32 // answer with this instead of 200 when non-zero
+	delay    atomic.Int64 // nanoseconds to sit on each request
+	searches atomic.Int32
+	audio    atomic.Int32
+	probes   atomic.Int32
+}
+
+func newTestBackend(t *testing.T) *testBackend {
+	tb := &testBackend{}
+	tb.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch q := r.URL.Query(); {
+		case q.Has("search"):
+			tb.searches.Add(1)
+		case q.Has("id"):
+			tb.audio.Add(1)
+		default:
+			tb.probes.Add(1)
+		}
+		if d := time.Duration(tb.delay.Load()); d > 0 {
+			select {
+			case <-time.After(d):
+			case <-r.Context().Done():
+				return
+			}
+		}
+		if code := tb.status.Load(); code != 0 {
+			w.WriteHeader(int(code))
+			return
+		}
+		if r.URL.Query().Has("search") {
+			io.WriteString(w, "[]")
+			return
+		}
+		w.Write(make([]byte, 64))
+	}))
+	t.Cleanup(tb.Close)
+	return tb
+}
+
+// newTestFetcher builds a fetcher over backends, retrying quickly.
+func newTestFetcher(t *testing.T, threshold int, cooldown time.Duration, backends ...BackendConfig) *httpFetcher {
+	raw, err := json.Marshal(backends)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := &config.Config{
+		FetchBackends:      string(raw),
+		FetchSearchTimeout: 200 * time.Millisecond,
+		FetchAudioTimeout:  200 * time.Millisecond,
+		FetchRetries:       3,
+		FetchBackoff:       time.Millisecond,
+		FetchBackoffMax:    5 * time.Millisecond,
+		BreakerThreshold:   threshold,
+		BreakerCooldown:    cooldown,
+	}
+	h, err := NewHTTPFetcher(cfg, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return h
+}
+
+func (h *httpFetcher) testSearch(t *testing.T) {
+	t.Helper()
+	if _, err := h.search("song", "test"); err != nil {
+		t.Fatalf("search: %v", err)
+	}
+}
+
+func (h *httpFetcher) testOpen(t *testing.T) {
+	t.Helper()
+	rc, err := h.Open(Song{ID: "test"}, 0)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	io.Copy(io.Discard, rc)
+	rc.Close()
+}
+
+// backendState is the endpoint at url as h's health report has it.
+func backendState(h *httpFetcher, url string) BackendHealth {
+	for _, bh := range h.Health().Backends {
+		if bh.URL == url {
+			return bh
+		}
+	}
+	return BackendHealth{}
+}
+
+func TestWeightedSplit(t *testing.T) {
+	a, b := newTestBackend(t), newTestBackend(t)
+	h := newTestFetcher(t, 5, time.Minute,
+		BackendConfig{URL: a.URL, Weight: 3},
+		BackendConfig{URL: b.URL, Weight: 1},
+	)
+	for i := 0; i < 40; i++ {
+		h.testSearch(t)
+	}
+	if got, want := a.searches.Load(), int32(30); got != want {
+		t.Errorf("weight 3 backend got %d searches, want %d", got, want)
+	}
+	if got, want := b.searches.Load(), int32(10); got != want {
+		t.Errorf("weight 1 backend got %d searches, want %d", got, want)
+	}
+}
+
+func TestFailover(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		fail func(*testBackend)
+	}{
+		{"5xx", func(tb *testBackend) { tb.status.Store(http.StatusBadGateway) }},
+		{"timeout", func(tb *testBackend) { tb.delay.Store(int64(time.Second)) }},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			bad, good := newTestBackend(t), newTestBackend(t)
+			tc.fail(bad)
+			h := newTestFetcher(t, 2, time.Minute,
+				BackendConfig{URL: bad.URL},
+				BackendConfig{URL: good.URL},
+			)
+			for i := 0; i < 6; i++ {
+				h.testSearch(t)
+				h.testOpen(t)
+			}
+			if got := good.searches.Load() + good.audio.Load(); got != 12 {
+				t.Errorf("healthy backend served %d requests, want 12", got)
+			}
+			// the breaker opens after two failures, so the rest skip it
+			if got := bad.searches.Load() + bad.audio.Load(); got != 2 {
+				t.Errorf("failing backend was tried %d times, want 2", got)
+			}
+			if st := backendState(h, bad.URL).State; st != ConverterDown {
+				t.Errorf("failing backend is %q, want %q", st, ConverterDown)
+			}
+			if st := h.Health().State; st != ConverterUp {
+				t.Errorf("converter is %q, want %q", st, ConverterUp)
+			}
+		})
+	}
+}
+
+func TestPreference(t *testing.T) {
+	search, audio, either := newTestBackend(t), newTestBackend(t), newTestBackend(t)
+	h := newTestFetcher(t, 1, time.Minute,
+		BackendConfig{URL: search.URL, Prefer: KindSearch},
+		BackendConfig{URL: audio.URL, Prefer: KindAudio},
+		BackendConfig{URL: either.URL},
+	)
+	for i := 0; i < 5; i++ {
+		h.testSearch(t)
+		h.testOpen(t)
+	}
+	if search.searches.Load() != 5 || audio.audio.Load() != 5 {
+		t.Errorf("preferred backends got %d searches and %d downloads, want 5 each",
+			search.searches.Load(), audio.audio.Load())
+	}
+	if search.audio.Load() != 0 || audio.searches.Load() != 0 || either.searches.Load()+either.audio.Load() != 0 {
+		t.Error("requests went past a preferred backend that was up")
+	}
+
+	// with the audio backend down, downloads go to the neutral one before
+	// the one preferred for searches
+	audio.status.Store(http.StatusServiceUnavailable)
+	for i := 0; i < 5; i++ {
+		h.testOpen(t)
+	}
+	if got := either.audio.Load(); got != 5 {
+		t.Errorf("neutral backend got %d downloads, want 5", got)
+	}
+	if got := search.audio.Load(); got != 0 {
+		t.Errorf("search backend got %d downloads, want 0", got)
+	}
+}
+
+func TestBreakerRecovery(t *testing.T) {
+	a := newTestBackend(t)
+	a.status.Store(http.StatusInternalServerError)
+	h := newTestFetcher(t, 1, 50*time.Millisecond, BackendConfig{URL: a.URL})
+
+	if _, err := h.search("song", "test"); err == nil {
+		t.Fatal("search against a failing backend succeeded")
+	}
+	if _, err := h.search("song", "test"); !errors.Is(err, ErrConverterDown) {
+		t.Fatalf("search with the breaker open: %v, want ErrConverterDown", err)
+	}
+	first := backendState(h, a.URL).RetryAt
+
+	// a health check that fails once the cooldown is over reopens it for longer
+	time.Sleep(60 * time.Millisecond)
+	h.probe(context.Background(), h.pool.backends[0])
+	bh := backendState(h, a.URL)
+	if bh.State != ConverterDown || !bh.RetryAt.After(first) {
+		t.Fatalf("after a failed health check: state %q retry at %v, want %q after %v", bh.State, bh.RetryAt, ConverterDown, first)
+	}
+
+	// once it answers again, CheckHealth brings it back without a request
+	a.status.Store(0)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go h.CheckHealth(ctx, 10*time.Millisecond)
+	deadline := time.Now().Add(2 * time.Second)
+	for h.Health().State != ConverterUp {
+		if time.Now().After(deadline) {
+			t.Fatalf("converter still %q after recovering", h.Health().State)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	h.testSearch(t)
+}
+
+func TestRefusedOpensBreaker(t *testing.T) {
+	a := newTestBackend(t)
+	a.status.Store(http.StatusUnauthorized)
+	h := newTestFetcher(t, 2, time.Minute, BackendConfig{URL: a.URL})
+	for i := 0; i < 2; i++ {
+		if _, err := h.search("song", "test"); err == nil {
+			t.Fatal("search against a refusing backend succeeded")
+		}
+	}
+	if got := a.searches.Load(); got != 2 {
+		t.Errorf("refused search was sent %d times, want 2 (no retries)", got)
+	}
+	if st := h.Health().State; st != ConverterDown {
+		t.Errorf("converter is %q after repeated refusals, want %q", st, ConverterDown)
+	}
+}
@@ -0,0 +1,143 @@
+package accessor
+
+import (
+	"errors"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// ErrConverterDown is returned without contacting the converter while the
+// circuit breaker is open.
+var ErrConverterDown = errors.New("converter service is down")
+
+// Breaker states as reported in ConverterHealth.
+const (
+	ConverterUp      = "up"
+	ConverterDown    = "down"    // breaker open; calls fail fast
+	ConverterProbing = "probing" // cooldown over; one call is let through to test it
+)
+
+// ConverterHealth is the converter service's state as the fetcher sees it:
+// up while any endpoint is.
+type ConverterHealth struct {
+	State    string          `json:"state"`
+	Backends []BackendHealth `json:"backends"`
+}
+
+// BackendHealth is one converter endpoint's state as its breaker sees it.
+type BackendHealth struct {
+	URL         string    `json:"url"`
+	Weight      int       `json:"weight"`
+	Prefer      string    `json:"prefer,omitempty"`
+	State       string    `json:"state"`
+	Failures    int       `json:"consecutive_failures"`
+	LastError   string    `json:"last_error,omitempty"`
+	LastErrorAt time.Time `json:"last_error_at,omitempty"`
+	RetryAt     time.Time `json:"retry_at,omitempty"` // when a down breaker lets a probe through
+}
+
+// HealthReporter is implemented by fetchers that track the converter's health.
+type HealthReporter interface {
+	Health() ConverterHealth
+}
+
+// breaker opens after threshold consecutive failures, fails calls fast for
+// cooldown, then lets a single probe through: success closes it again,
+// failure reopens it.
+type breaker struct {
+	mu        sync.Mutex
+	threshold int
+	cooldown  time.Duration
+	failures  int
+	openUntil time.Time // zero while closed
+	probing   bool
+	lastErr   error
+	lastErrAt time.Time
+	onChange  func(up bool)
+}
+
+func newBreaker(threshold int, cooldown time.Duration, onChange func(up bool)) *breaker {
+	return &breaker{threshold: threshold, cooldown: cooldown, onChange: onChange}
+}
+
+// allow reports whether a call may go ahead, taking the probe slot if the
+// cooldown is over.
+func (b *breaker) allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.openUntil.IsZero() {
+		return nil
+	}
+	if b.probing || time.Now().Before(b.openUntil) {
+		return ErrConverterDown
+	}
+	b.probing = true
+	return nil
+}
+
+// available is allow without taking the probe slot.
+func (b *breaker) available() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.openUntil.IsZero() || (!b.probing && !time.Now().Before(b.openUntil))
+}
+
+func (b *breaker) success() {
+	b.mu.Lock()
+	wasOpen := !b.openUntil.IsZero()
+	b.failures, b.openUntil, b.probing = 0, time.Time{}, false
+	b.mu.Unlock()
+	if wasOpen {
+		b.onChange(true)
+	}
+}
+
+func (b *breaker) failure(err error) {
+	b.mu.Lock()
+	b.failures++
+	b.lastErr, b.lastErrAt = err, time.Now()
+	opened := false
+	// a failed probe (or health check once the cooldown is over) reopens it
+	expired := !b.openUntil.IsZero() && !time.Now().Before(b.openUntil)
+	if b.probing || expired || (b.openUntil.IsZero() && b.threshold > 0 && b.failures >= b.threshold) {
+		opened = b.openUntil.IsZero()
+		b.openUntil, b.probing = time.Now().Add(b.cooldown), false
+	}
+	b.mu.Unlock()
+	if opened {
+		b.onChange(false)
+	}
+}
+
+func (b *breaker) health() BackendHealth {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	h := BackendHealth{State: ConverterUp, Failures: b.failures, LastErrorAt: b.lastErrAt}
+	if b.lastErr != nil {
+		h.LastError = b.lastErr.Error()
+	}
+	switch {
+	case b.probing:
+		h.State = ConverterProbing
+	case !b.openUntil.IsZero():
+		h.State, h.RetryAt = ConverterDown, b.openUntil
+	}
+	return h
+}
+
+// Backoff is the delay before retry number attempt (from 0): base doubled
+// per attempt, capped at max, with full jitter so callers don't retry in step.
+func Backoff(attempt int, base, max time.Duration) time.Duration {
+	d := base
+	for i := 0; i < attempt && d < max; i++ {
+		d *= 2
+	}
+	if d > max {
+		d = max
+	}
+	if d <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(d)) + 1)
+}
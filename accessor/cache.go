@@ -0,0 +1,60 @@
+package accessor
+
+import (
+	"container/list"
+	"sync"
+)
+
+// audioCache is a small LRU of raw audio keyed by song ID, so a track that
+// is fetched twice in quick succession (startup, replays) hits the converter once.
+type audioCache struct {
+	mu    sync.Mutex
+	max   int
+	order *list.List               // front = most recently used
+	items map[string]*list.Element // songID → element holding *cacheEntry
+}
+
+type cacheEntry struct {
+	id   string
+	data []byte
+}
+
+func newAudioCache(max int) *audioCache {
+	return &audioCache{
+		max:   max,
+		order: list.New(),
+		items: make(map[string]*list.Element),
+	}
+}
+
+func (c *audioCache) get(id string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.items[id]
+	if !ok {
+		cacheMisses.Inc()
+		return nil, false
+	}
+	cacheHits.Inc()
+	c.order.MoveToFront(el)
+	return el.Value.(*cacheEntry).data, true
+}
+
+func (c *audioCache) put(id string, data []byte) {
+	if c.max <= 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.items[id]; ok {
+		el.Value.(*cacheEntry).data = data
+		c.order.MoveToFront(el)
+		return
+	}
+	c.items[id] = c.order.PushFront(&cacheEntry{id: id, data: data})
+	for c.order.Len() > c.max {
+		old := c.order.Back()
+		c.order.Remove(old)
+		delete(c.items, old.Value.(*cacheEntry).id)
+	}
+}
@@ -2,16 +2,23 @@
 package accessor
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
-	"log"
+	"log/slog"
 	"net/http"
+	"net/url"
 	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
 
+	"github.com/Coop25/CC-Radio/chunker"
 	"github.com/Coop25/CC-Radio/config"
+	"github.com/Coop25/CC-Radio/logging"
 )
 
 // PlaylistItem mirrors one element of "playlist_items"
@@ -27,26 +34,55 @@ type rawSong struct {
 	Artist string `json:"artist"` // "MM:SS � ArtistName"
 }
 
-// Fetcher knows how to GET raw audio bytes by song ID.
-type Fetcher interface {
-	FetchBytes(songID string) ([]byte, error)
-	LoadPlaylist(playlistURL string) error
-	LoadSong(requestURL string) error
-	LoadRadioSegment(requestURL string) error
+// LoadResult counts what a loader did with the items the converter returned.
+type LoadResult struct {
+	Added      int
+	Duplicates int // already in the list
+	Skipped    int // unparseable entries
 }
 
-// httpFetcher implements Fetcher over HTTP.
+// Resolved is the metadata a Resolver found for one query.
+type Resolved struct {
+	Songs   []Song
+	Skipped int // unparseable entries
+}
+
+// Resolver turns a song/search URL or a playlist URL into song metadata.
+// It never touches a Playlist; callers decide where the songs go.
+type Resolver interface {
+	Resolve(requestURL string) (Resolved, error)
+	ResolvePlaylist(playlistURL string) (Resolved, error)
+}
+
+// AudioSource opens the DFPWM audio stream for a song, off bytes in.
+type AudioSource interface {
+	Open(song Song, off int64) (io.ReadCloser, error)
+}
+
+// httpFetcher implements Resolver and AudioSource over the converter's HTTP
+// API, spreading requests over one or more converter endpoints.
 type httpFetcher struct {
-	baseURL  string
-	client   *http.Client
+	pool     *pool
+	client   *http.Client // no overall timeout; each operation sets its own
 	headers  http.Header
-	playlist *Playlist
+	cache    *audioCache
+	cacheMax int // bytes; longer tracks aren't cached
+	log      *slog.Logger
+
+	searchTimeout time.Duration
+	audioTimeout  time.Duration
+	retries       int
+	backoff       time.Duration
+	backoffMax    time.Duration
 }
 
 // NewHTTPFetcher builds one using your Config.
-func NewHTTPFetcher(cfg *config.Config, pl *Playlist) *httpFetcher {
-	// shared HTTP client with a reasonable timeout
-	cli := &http.Client{Timeout: 10 * time.Second}
+func NewHTTPFetcher(cfg *config.Config, log *slog.Logger) (*httpFetcher, error) {
+	log = logging.Component(log, "fetcher")
+	backends, err := parseBackends(cfg.FetchBackends, cfg.FetchBaseURL)
+	if err != nil {
+		return nil, err
+	}
 
 	// preset headers for every request
 	hdrs := make(http.Header)
@@ -54,219 +90,353 @@ func NewHTTPFetcher(cfg *config.Config, pl *Playlist) *httpFetcher {
 	hdrs.Set("Accept-Charset", "UTF-8")
 	hdrs.Set("Connection", "keep-alive")
 
-	return &httpFetcher{
-		baseURL:  cfg.FetchBaseURL,
-		client:   cli,
+	h := &httpFetcher{
+		pool:     &pool{},
+		client:   &http.Client{},
 		headers:  hdrs,
-		playlist: pl,
+		cache:    newAudioCache(cfg.AudioCacheSize),
+		cacheMax: int(cfg.AudioCacheMaxLen.Seconds() * chunker.BytesPerSecond),
+		log:      log,
+
+		searchTimeout: cfg.FetchSearchTimeout,
+		audioTimeout:  cfg.FetchAudioTimeout,
+		retries:       cfg.FetchRetries,
+		backoff:       cfg.FetchBackoff,
+		backoffMax:    cfg.FetchBackoffMax,
+	}
+	for _, bc := range backends {
+		url := bc.URL
+		h.pool.backends = append(h.pool.backends, &backend{
+			BackendConfig: bc,
+			breaker: newBreaker(cfg.BreakerThreshold, cfg.BreakerCooldown, func(up bool) {
+				if up {
+					log.Info("converter endpoint is back up", "backend", url)
+				} else {
+					log.Error("converter endpoint is down; failing over", "backend", url, "cooldown", cfg.BreakerCooldown)
+				}
+				h.updateUp()
+			}),
+		})
 	}
+	converterUp.Set(1)
+	return h, nil
 }
 
-func (h *httpFetcher) FetchBytes(songID string) ([]byte, error) {
-	// build URL with ?id=<songID>
-	req, err := http.NewRequest("GET", h.baseURL+"?v=2&id="+songID, nil)
-	if err != nil {
-		return nil, err
+// Health reports each converter endpoint's state as seen by its circuit breaker.
+func (h *httpFetcher) Health() ConverterHealth {
+	return h.pool.health()
+}
+
+// updateUp refreshes the converter_up gauge after an endpoint changes state.
+func (h *httpFetcher) updateUp() {
+	if h.pool.health().State == ConverterDown {
+		converterUp.Set(0)
+	} else {
+		converterUp.Set(1)
 	}
+}
 
-	// apply shared headers
-	req.Header = h.headers
+// statusError is a non-200 answer from the converter.
+type statusError struct {
+	code   int
+	status string
+	body   []byte
+}
 
-	resp, err := h.client.Do(req)
-	if err != nil {
-		return nil, err
+func (e *statusError) Error() string {
+	if len(e.body) == 0 {
+		return "unexpected status " + e.status
 	}
-	defer resp.Body.Close()
+	return fmt.Sprintf("status %s, body %q", e.status, e.body)
+}
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("fetch bytes: unexpected status %s", resp.Status)
+// retryable reports whether err is worth another attempt: transport
+// failures, timeouts and server errors are; the converter turning the
+// request down is not.
+func retryable(err error) bool {
+	var se *statusError
+	if errors.As(err, &se) {
+		return se.code >= 500 || se.code == http.StatusTooManyRequests
 	}
-	return io.ReadAll(resp.Body)
+	return true
 }
 
-// LoadByID fetches the JSON for a playlist, parses durations and names,
-// and adds each Song into h.playlist (then shuffles).
-func (h *httpFetcher) LoadPlaylist(playlistURL string) error {
-	// build the GET request to the provided URL
-	req, err := http.NewRequest("GET", h.baseURL, nil)
-	if err != nil {
-		return fmt.Errorf("load playlist: %w", err)
+// refused reports whether the converter turned the request away: it won't
+// do better on a retry, but the endpoint is no use until it is fixed.
+func refused(err error) bool {
+	var se *statusError
+	return errors.As(err, &se) && (se.code == http.StatusUnauthorized || se.code == http.StatusForbidden)
+}
+
+// call runs attempt until it succeeds, fails for good or runs out of
+// retries. Each try goes to the next endpoint for kind, failing over
+// straight away while there are endpoints this call hasn't tried and
+// backing off before going round them again. Retryable and refused
+// failures count toward opening that endpoint's breaker; with every
+// breaker open call fails fast with ErrConverterDown.
+func (h *httpFetcher) call(op, kind string, attempt func(base string) error) error {
+	tried := make(map[*backend]bool)
+	var err error
+	for try, round := 0, 0; try <= h.retries; try++ {
+		be := h.pool.pick(kind, tried)
+		if be == nil && len(tried) > 0 {
+			d := Backoff(round, h.backoff, h.backoffMax)
+			round++
+			h.log.Warn("converter request failed on every endpoint; retrying", "op", op, "in", d, "err", err)
+			time.Sleep(d)
+			tried = make(map[*backend]bool)
+			be = h.pool.pick(kind, tried)
+		}
+		if be == nil {
+			return h.pool.downError()
+		}
+		tried[be] = true
+		if try > 0 {
+			fetchRetries.Inc(op)
+		}
+		err = attempt(be.URL)
+		if err == nil || !retryable(err) && !refused(err) {
+			be.breaker.success()
+			return err
+		}
+		be.breaker.failure(err)
+		h.log.Warn("converter request failed", "op", op, "backend", be.URL, "attempt", try+1, "err", err)
+		if !retryable(err) {
+			return err
+		}
 	}
-	q := req.URL.Query()
-	q.Set("v", "2")
-	q.Set("search", playlistURL)
-	req.URL.RawQuery = q.Encode()
-	// apply the same headers as FetchBytes
-	req.Header = h.headers
+	return err
+}
 
+// do runs req against the converter, recording latency (to the response
+// headers, for streamed bodies) and failures under op. A non-200 answer is
+// returned as a *statusError with the body closed.
+func (h *httpFetcher) do(op string, req *http.Request) (*http.Response, error) {
+	start := time.Now()
 	resp, err := h.client.Do(req)
+	fetchDuration.Observe(op, time.Since(start).Seconds())
 	if err != nil {
-		return fmt.Errorf("load playlist: %w", err)
+		fetchFailures.Inc(op)
+		return nil, err
 	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("load playlist: status %s, body: %q", resp.Status, body)
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusPartialContent {
+		fetchFailures.Inc(op)
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		resp.Body.Close()
+		return nil, &statusError{code: resp.StatusCode, status: resp.Status, body: body}
 	}
+	return resp, nil
+}
 
-	data, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return fmt.Errorf("reading playlist response: %w", err)
+// Open streams song's audio from the converter, or from the cache if it was
+// fetched recently. A stream read through to the end is cached unless it
+// runs past cacheMax. Past the start it asks for a byte range, skipping
+// ahead itself if the converter sends the whole file anyway.
+func (h *httpFetcher) Open(song Song, off int64) (io.ReadCloser, error) {
+	if data, ok := h.cache.get(song.ID); ok {
+		return io.NopCloser(bytes.NewReader(data[min(off, int64(len(data))):])), nil
 	}
 
-	// parse JSON
-	var raws []struct {
-		PlaylistItems []rawSong `json:"playlist_items"`
-	}
-	if err := json.Unmarshal(data, &raws); err != nil {
-		return fmt.Errorf("invalid playlist JSON: %w", err)
-	}
-	if len(raws) == 0 {
-		return fmt.Errorf("no playlist data in response")
-	}
+	var body io.ReadCloser
+	err := h.call("bytes", KindAudio, func(base string) error {
+		// build URL with ?id=<songID>
+		req, err := http.NewRequest("GET", base+"?v=2&id="+song.ID, nil)
+		if err != nil {
+			return err
+		}
 
-	songs, err := parseSongs(raws[0].PlaylistItems)
-	if err != nil {
-		log.Printf("[Fetcher] ReadAll error: %v", err)
-		return fmt.Errorf("reading load songs response: %w", err)
-	}
+		// apply shared headers
+		req.Header = h.headers
+		if off > 0 {
+			req.Header = h.headers.Clone()
+			req.Header.Set("Range", fmt.Sprintf("bytes=%d-", off))
+		}
 
-	// enqueue
-	for _, s := range songs {
-		h.playlist.Add(s)
+		// the body is read at playback pace, so only waits are timed: for
+		// the headers here, and for each read in stallBody
+		ctx, cancel := context.WithCancel(context.Background())
+		timer := time.AfterFunc(h.audioTimeout, cancel)
+		resp, err := h.do("bytes", req.WithContext(ctx))
+		timer.Stop()
+		var se *statusError
+		if off > 0 && errors.As(err, &se) && se.code == http.StatusRequestedRangeNotSatisfiable {
+			// off is at or past the end
+			cancel()
+			body = io.NopCloser(bytes.NewReader(nil))
+			return nil
+		}
+		if err != nil {
+			cancel()
+			return err
+		}
+		body = &stallBody{body: resp.Body, timeout: h.audioTimeout, cancel: cancel}
+		if resp.StatusCode == http.StatusPartialContent {
+			return nil // a partial file isn't cached
+		}
+		if h.cacheMax > 0 && resp.ContentLength <= int64(h.cacheMax) {
+			body = &cachingBody{body: body, max: h.cacheMax, done: func(data []byte) { h.cache.put(song.ID, data) }}
+		}
+		if _, err := io.CopyN(io.Discard, body, off); err != nil && err != io.EOF {
+			body.Close()
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("fetch bytes: %w", err)
 	}
+	return body, nil
+}
 
-	return nil
+// stallBody fails a read that waits longer than timeout for data, so a
+// converter that stops sending mid-track doesn't hang the stream.
+type stallBody struct {
+	body    io.ReadCloser
+	timeout time.Duration
+	cancel  context.CancelFunc
+	stalled atomic.Bool
 }
 
-func (h *httpFetcher) LoadSong(requestURL string) error {
-	log.Printf("[Fetcher] Fetching songs JSON from %s", requestURL)
+func (s *stallBody) Read(p []byte) (int, error) {
+	timer := time.AfterFunc(s.timeout, func() {
+		s.stalled.Store(true)
+		s.cancel()
+	})
+	n, err := s.body.Read(p)
+	timer.Stop()
+	if err != nil && err != io.EOF && s.stalled.Load() {
+		err = fmt.Errorf("no audio for %s: %w", s.timeout, err)
+	}
+	return n, err
+}
 
-	req, err := http.NewRequest("GET", h.baseURL, nil)
-	if err != nil {
-		log.Printf("[Fetcher] NewRequest error: %v", err)
-		return fmt.Errorf("load songs: %w", err)
-	}
-	q := req.URL.Query()
-	q.Set("v", "2")
-	q.Set("search", requestURL)
-	req.URL.RawQuery = q.Encode()
+func (s *stallBody) Close() error {
+	s.cancel()
+	return s.body.Close()
+}
 
-	req.Header = h.headers
+// cachingBody keeps a copy of what is read from body and hands it to done
+// once the stream ends cleanly, giving up on the copy past max bytes.
+type cachingBody struct {
+	body io.ReadCloser
+	buf  bytes.Buffer
+	max  int
+	done func([]byte)
+}
 
-	// do request
-	resp, err := h.client.Do(req)
-	if err != nil {
-		log.Printf("[Fetcher] HTTP error: %v", err)
-		return fmt.Errorf("load songs: %w", err)
+func (c *cachingBody) Read(p []byte) (int, error) {
+	n, err := c.body.Read(p)
+	if c.done != nil {
+		c.buf.Write(p[:n])
+		if c.buf.Len() > c.max {
+			c.buf, c.done = bytes.Buffer{}, nil
+		}
 	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("load songs: status %s, body %q", resp.Status, body)
+	switch {
+	case err == io.EOF && c.done != nil:
+		c.done(c.buf.Bytes())
+		c.done = nil
+	case err != nil && err != io.EOF:
+		fetchFailures.Inc("bytes")
 	}
+	return n, err
+}
 
-	// read payload
-	data, err := io.ReadAll(resp.Body)
+func (c *cachingBody) Close() error { return c.body.Close() }
+
+// Resolve looks up a song URL or search term and returns the songs it names.
+func (h *httpFetcher) Resolve(requestURL string) (Resolved, error) {
+	data, err := h.search("song", requestURL)
 	if err != nil {
-		log.Printf("[Fetcher] ReadAll error: %v", err)
-		return fmt.Errorf("reading load songs response: %w", err)
+		return Resolved{}, fmt.Errorf("resolve songs: %w", err)
 	}
 
-	log.Printf("[Fetcher] Response status: %s, body length: %d", resp.Status, len(data))
-
 	var rawParse []rawSong
 	if err := json.Unmarshal(data, &rawParse); err != nil {
-		log.Printf("[Fetcher] JSON unmarshal error: %v", err)
-		return fmt.Errorf("invalid songs JSON: %w", err)
-	}
-
-	songs, err := parseSongs(rawParse)
-	if err != nil {
-		log.Printf("[Fetcher] ReadAll error: %v", err)
-		return fmt.Errorf("reading load songs response: %w", err)
-	}
-
-	// enqueue
-	for _, s := range songs {
-		h.playlist.Add(s)
+		h.log.Error("decode songs JSON failed", "err", err)
+		return Resolved{}, fmt.Errorf("invalid songs JSON: %w", err)
 	}
-
-	return nil
+	return h.resolved(rawParse)
 }
 
-func (h *httpFetcher) LoadRadioSegment(requestURL string) error {
-	log.Printf("[Fetcher] Fetching songs JSON from %s", requestURL)
-
-	req, err := http.NewRequest("GET", h.baseURL, nil)
+// ResolvePlaylist fetches the JSON for a playlist and returns its songs.
+func (h *httpFetcher) ResolvePlaylist(playlistURL string) (Resolved, error) {
+	data, err := h.search("playlist", playlistURL)
 	if err != nil {
-		log.Printf("[Fetcher] NewRequest error: %v", err)
-		return fmt.Errorf("load songs: %w", err)
+		return Resolved{}, fmt.Errorf("resolve playlist: %w", err)
 	}
-	q := req.URL.Query()
-	q.Set("v", "2")
-	q.Set("search", requestURL)
-	req.URL.RawQuery = q.Encode()
-
-	req.Header = h.headers
 
-	// do request
-	resp, err := h.client.Do(req)
-	if err != nil {
-		log.Printf("[Fetcher] HTTP error: %v", err)
-		return fmt.Errorf("load radioSegment: %w", err)
+	var raws []struct {
+		PlaylistItems []rawSong `json:"playlist_items"`
 	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("load radioSegment: status %s, body %q", resp.Status, body)
+	if err := json.Unmarshal(data, &raws); err != nil {
+		return Resolved{}, fmt.Errorf("invalid playlist JSON: %w", err)
 	}
-
-	// read payload
-	data, err := io.ReadAll(resp.Body)
-	if err != nil {
-		log.Printf("[Fetcher] ReadAll error: %v", err)
-		return fmt.Errorf("reading load radioSegment response: %w", err)
+	if len(raws) == 0 {
+		return Resolved{}, fmt.Errorf("no playlist data in response")
 	}
+	return h.resolved(raws[0].PlaylistItems)
+}
 
-	log.Printf("[Fetcher] Response status: %s, body length: %d", resp.Status, len(data))
+// search asks the converter about query and returns the raw JSON payload.
+func (h *httpFetcher) search(op, query string) ([]byte, error) {
+	h.log.Info("fetching songs JSON", "op", op, "url", query)
 
-	var rawParse []rawSong
-	if err := json.Unmarshal(data, &rawParse); err != nil {
-		log.Printf("[Fetcher] JSON unmarshal error: %v", err)
-		return fmt.Errorf("invalid radioSegment JSON: %w", err)
-	}
+	q := url.Values{}
+	q.Set("v", "2")
+	q.Set("search", query)
 
-	songs, err := parseSongs(rawParse)
+	var data []byte
+	err := h.call(op, KindSearch, func(base string) error {
+		ctx, cancel := context.WithTimeout(context.Background(), h.searchTimeout)
+		defer cancel()
+		req, err := http.NewRequestWithContext(ctx, "GET", base+"?"+q.Encode(), nil)
+		if err != nil {
+			return err
+		}
+		req.Header = h.headers
+		resp, err := h.do(op, req)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		data, err = io.ReadAll(resp.Body)
+		return err
+	})
 	if err != nil {
-		log.Printf("[Fetcher] ReadAll error: %v", err)
-		return fmt.Errorf("reading load radioSegment response: %w", err)
+		h.log.Error("request failed", "url", query, "err", err)
+		return nil, err
 	}
+	h.log.Debug("converter responded", "bytes", len(data))
+	return data, nil
+}
 
-	// enqueue
-	for _, s := range songs {
-		h.playlist.AddRadio(s)
+func (h *httpFetcher) resolved(items []rawSong) (Resolved, error) {
+	songs, skipped, err := parseSongs(items)
+	if err != nil {
+		h.log.Error("parse songs failed", "err", err)
+		return Resolved{}, err
 	}
-
-	return nil
+	return Resolved{Songs: songs, Skipped: skipped}, nil
 }
 
-func parseSongs(data []rawSong) ([]Song, error) {
+// parseSongs converts raw items, also returning how many it had to skip.
+func parseSongs(data []rawSong) ([]Song, int, error) {
 	// 2) Convert into []Song
 	out := make([]Song, 0, len(data))
+	skipped := 0
 	for _, item := range data {
 		// split off the time prefix
 		parts := strings.SplitN(item.Artist, " ", 2)
 		if len(parts) < 1 {
 			// no timestamp? skip
+			skipped++
 			continue
 		}
 		dur, err := parseDuration(parts[0])
 		if err != nil {
 			// malformed time? skip
+			skipped++
 			continue
 		}
 
@@ -279,9 +449,9 @@ func parseSongs(data []rawSong) ([]Song, error) {
 		})
 	}
 	if len(out) == 0 {
-		return out, fmt.Errorf("no songs added")
+		return out, skipped, fmt.Errorf("no songs added")
 	}
-	return out, nil
+	return out, skipped, nil
 }
 
 // parseDuration turns "MM:SS" into time.Duration.
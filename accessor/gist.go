@@ -3,33 +3,69 @@ package accessor
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/Coop25/CC-Radio/config"
+	"github.com/Coop25/CC-Radio/logging"
 )
 
 // playlistBackup matches the snapshot format
 type playlistBackup struct {
 	Queue      []Song `json:"queue"`
 	RandomNext []Song `json:"random_next"`
+	// tag expression limiting the master rotation; empty plays everything
+	RotationFilter string `json:"rotation_filter,omitempty"`
 }
 
 type GistAccessor struct {
 	token  string
 	gistID string
 	client *http.Client
+	log    *slog.Logger
+
+	mu          sync.Mutex
+	loadedAt    time.Time
+	lastSaveAt  time.Time
+	lastSaveErr error
+}
+
+// StorageStatus is what the Gist accessor reports for readiness checks.
+type StorageStatus struct {
+	Loaded      bool      `json:"loaded"`
+	LoadedAt    time.Time `json:"loaded_at"`
+	LastSaveAt  time.Time `json:"last_save_at,omitempty"`
+	LastSaveErr string    `json:"last_save_error,omitempty"`
+}
+
+// Status reports whether the playlist has been loaded and how the last save went.
+func (g *GistAccessor) Status() StorageStatus {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	st := StorageStatus{
+		Loaded:     !g.loadedAt.IsZero(),
+		LoadedAt:   g.loadedAt,
+		LastSaveAt: g.lastSaveAt,
+	}
+	if g.lastSaveErr != nil {
+		st.LastSaveErr = g.lastSaveErr.Error()
+	}
+	return st
 }
 
-func NewGistAccessor(cfg *config.Config) *GistAccessor {
+func NewGistAccessor(cfg *config.Config, log *slog.Logger) *GistAccessor {
 	return &GistAccessor{
 		token:  cfg.GITHUB_TOKEN,
 		gistID: cfg.GITHUB_GIST_ID,
 		client: &http.Client{Timeout: 10 * time.Second},
+		log:    logging.Component(log, "gist"),
 	}
 }
 
@@ -41,16 +77,86 @@ func (g *GistAccessor) SavePlaylist(pl *Playlist) error {
 		Queue:      append([]Song(nil), pl.queue...),
 		RandomNext: append([]Song(nil), pl.randomNext...),
 	}
+	if pl.rotation != nil {
+		backup.RotationFilter = pl.rotation.String()
+	}
 	pl.mu.Unlock()
 
-	blob, err := json.MarshalIndent(backup, "", "  ")
+	return g.recordSave("playlist.json", g.patchFile("playlist.json", backup))
+}
+
+// SavePermissions PATCHes the existing gist, replacing permissions.json
+func (g *GistAccessor) SavePermissions(p *Permissions) error {
+	return g.recordSave("permissions.json", g.patchFile("permissions.json", p.backup()))
+}
+
+// SaveRatings PATCHes the existing gist, replacing ratings.json
+func (g *GistAccessor) SaveRatings(r *Ratings) error {
+	return g.recordSave("ratings.json", g.patchFile("ratings.json", r.backup()))
+}
+
+// SaveSchedule PATCHes the existing gist, replacing schedule.json
+func (g *GistAccessor) SaveSchedule(s *Schedule) error {
+	return g.recordSave("schedule.json", g.patchFile("schedule.json", s.backup()))
+}
+
+// PanelRef locates the now-playing panel message, so a restart edits it
+// instead of posting another.
+type PanelRef struct {
+	ChannelID string `json:"channel_id"`
+	MessageID string `json:"message_id"`
+}
+
+// SavePanel PATCHes the existing gist, replacing panel.json
+func (g *GistAccessor) SavePanel(ref PanelRef) error {
+	return g.recordSave("panel.json", g.patchFile("panel.json", ref))
+}
+
+// AutoSaveRatings writes ratings.json every interval while there are unsaved changes.
+func (g *GistAccessor) AutoSaveRatings(ctx context.Context, r *Ratings, interval time.Duration) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			if !r.takeDirty() {
+				continue
+			}
+			if err := g.SaveRatings(r); err != nil {
+				r.markDirty() // try again next round
+			}
+		}
+	}
+}
+
+// recordSave updates save status and metrics for one file write.
+func (g *GistAccessor) recordSave(file string, err error) error {
+	g.mu.Lock()
+	g.lastSaveAt = time.Now()
+	g.lastSaveErr = err
+	g.mu.Unlock()
+	if err != nil {
+		gistSaves.Inc("failure")
+		g.log.Error("save failed", "file", file, "err", err)
+	} else {
+		gistSaves.Inc("success")
+		g.log.Debug("saved", "file", file)
+	}
+	return err
+}
+
+// patchFile marshals v and writes it to one file of the gist.
+func (g *GistAccessor) patchFile(name string, v interface{}) error {
+	blob, err := json.MarshalIndent(v, "", "  ")
 	if err != nil {
 		return err
 	}
 
 	payload := map[string]interface{}{
 		"files": map[string]map[string]string{
-			"playlist.json": {
+			name: {
 				"content": string(blob),
 			},
 		},
@@ -86,34 +192,43 @@ type gistResponse struct {
 	Files map[string]gistFile `json:"files"`
 }
 
-func (g *GistAccessor) LoadByID(pl *Playlist) error {
+// fetchFiles GETs the gist and returns its files by name.
+func (g *GistAccessor) fetchFiles() (map[string]gistFile, error) {
 	// 1) Call the GitHub API to get the Gist JSON
 	url := fmt.Sprintf("https://api.github.com/gists/%s", g.gistID)
 	req, err := http.NewRequest("GET", url, nil)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	req.Header.Set("Authorization", "token "+g.token)
 	req.Header.Set("Accept", "application/vnd.github.v3+json")
 
 	resp, err := g.client.Do(req)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("gist load failed: %s", resp.Status)
+		return nil, fmt.Errorf("gist load failed: %s", resp.Status)
 	}
 
 	// 2) Decode the response into our struct
 	var gr gistResponse
 	if err := json.NewDecoder(resp.Body).Decode(&gr); err != nil {
-		return fmt.Errorf("invalid Gist JSON: %w", err)
+		return nil, fmt.Errorf("invalid Gist JSON: %w", err)
+	}
+	return gr.Files, nil
+}
+
+func (g *GistAccessor) LoadByID(pl *Playlist) error {
+	files, err := g.fetchFiles()
+	if err != nil {
+		return err
 	}
 
 	// 3) Extract the content of "playlist.json"
-	file, ok := gr.Files["playlist.json"]
+	file, ok := files["playlist.json"]
 	if !ok {
 		return fmt.Errorf("gist does not contain playlist.json")
 	}
@@ -129,6 +244,85 @@ func (g *GistAccessor) LoadByID(pl *Playlist) error {
 	pl.queue = backup.Queue
 	pl.randomNext = backup.RandomNext
 	pl.mu.Unlock()
+	if err := pl.SetRotationFilter(backup.RotationFilter); err != nil {
+		g.log.Warn("ignoring saved rotation filter", "filter", backup.RotationFilter, "err", err)
+	}
+
+	g.mu.Lock()
+	g.loadedAt = time.Now()
+	g.mu.Unlock()
 
 	return nil
 }
+
+// LoadPermissions replaces p with permissions.json; a gist without one keeps the config defaults.
+func (g *GistAccessor) LoadPermissions(p *Permissions) error {
+	files, err := g.fetchFiles()
+	if err != nil {
+		return err
+	}
+	file, ok := files["permissions.json"]
+	if !ok {
+		return nil
+	}
+	var backup permissionsBackup
+	if err := json.Unmarshal([]byte(file.Content), &backup); err != nil {
+		return fmt.Errorf("invalid permissions JSON in gist: %w", err)
+	}
+	if dropped := p.restore(backup); len(dropped) > 0 {
+		g.log.Warn("ignoring invalid levels in permissions.json; using defaults", "commands", dropped)
+	}
+	return nil
+}
+
+// LoadRatings replaces r with ratings.json; a gist without one starts empty.
+func (g *GistAccessor) LoadRatings(r *Ratings) error {
+	files, err := g.fetchFiles()
+	if err != nil {
+		return err
+	}
+	file, ok := files["ratings.json"]
+	if !ok {
+		return nil
+	}
+	var backup ratingsBackup
+	if err := json.Unmarshal([]byte(file.Content), &backup); err != nil {
+		return fmt.Errorf("invalid ratings JSON in gist: %w", err)
+	}
+	r.restore(backup)
+	return nil
+}
+
+// LoadSchedule replaces s's blocks with schedule.json; a gist without one keeps SCHEDULE from config.
+func (g *GistAccessor) LoadSchedule(s *Schedule) error {
+	files, err := g.fetchFiles()
+	if err != nil {
+		return err
+	}
+	file, ok := files["schedule.json"]
+	if !ok {
+		return nil
+	}
+	var backup scheduleBackup
+	if err := json.Unmarshal([]byte(file.Content), &backup); err != nil {
+		return fmt.Errorf("invalid schedule JSON in gist: %w", err)
+	}
+	return s.replace(backup.Blocks)
+}
+
+// LoadPanel reads panel.json; a gist without one returns an empty ref.
+func (g *GistAccessor) LoadPanel() (PanelRef, error) {
+	files, err := g.fetchFiles()
+	if err != nil {
+		return PanelRef{}, err
+	}
+	file, ok := files["panel.json"]
+	if !ok {
+		return PanelRef{}, nil
+	}
+	var ref PanelRef
+	if err := json.Unmarshal([]byte(file.Content), &ref); err != nil {
+		return PanelRef{}, fmt.Errorf("invalid panel JSON in gist: %w", err)
+	}
+	return ref, nil
+}
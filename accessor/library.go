@@ -0,0 +1,126 @@
+package accessor
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Songs returns a copy of the master list in insertion order.
+func (p *Playlist) Songs() []Song {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]Song(nil), p.queue...)
+}
+
+// RadioSegments returns a copy of the radio‐segment list in insertion order.
+func (p *Playlist) RadioSegments() []Song {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]Song(nil), p.randomNext...)
+}
+
+// Lookup finds a song or radio segment by ID.
+func (p *Playlist) Lookup(id string) (Song, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, list := range [][]Song{p.queue, p.randomNext} {
+		for _, s := range list {
+			if s.ID == id {
+				return s, true
+			}
+		}
+	}
+	return Song{}, false
+}
+
+// Match is one search hit.
+type Match struct {
+	Song  Song
+	Radio bool // found in randomNext rather than the master list
+	Score int
+}
+
+// Search fuzzy-matches query against name and artist across both lists,
+// best hits first. limit <= 0 returns every hit.
+func (p *Playlist) Search(query string, limit int) []Match {
+	p.mu.Lock()
+	var out []Match
+	for _, s := range p.queue {
+		if sc := songScore(query, s); sc > 0 {
+			out = append(out, Match{Song: s, Score: sc})
+		}
+	}
+	for _, s := range p.randomNext {
+		if sc := songScore(query, s); sc > 0 {
+			out = append(out, Match{Song: s, Radio: true, Score: sc})
+		}
+	}
+	p.mu.Unlock()
+
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Score > out[j].Score })
+	if limit > 0 && len(out) > limit {
+		out = out[:limit]
+	}
+	return out
+}
+
+// songScore is the best of the name, artist and ID scores; an exact ID wins outright.
+func songScore(query string, s Song) int {
+	q := strings.TrimSpace(query)
+	if q == "" {
+		return 1
+	}
+	if q == s.ID {
+		return 1000
+	}
+	best := fuzzyScore(q, s.Name)
+	if sc := fuzzyScore(q, s.Artist); sc > best {
+		best = sc
+	}
+	return best
+}
+
+// fuzzyScore rates how well query matches text: 0 is no match.
+// Substrings score highest (more at word starts); otherwise every
+// non-space query rune must appear in order, with bonuses for runs and word starts.
+func fuzzyScore(query, text string) int {
+	lq := strings.ToLower(query)
+	lt := strings.ToLower(text)
+	if lq == "" || lt == "" {
+		return 0
+	}
+
+	if i := strings.Index(lt, lq); i >= 0 {
+		score := 100 + 2*utf8.RuneCountInString(lq)
+		if r, _ := utf8.DecodeLastRuneInString(lt[:i]); i == 0 || !isWordRune(r) {
+			score += 20
+		}
+		return score
+	}
+
+	q := []rune(strings.Join(strings.Fields(lq), ""))
+	t := []rune(lt)
+	score, qi, run := 0, 0, 0
+	for ti := 0; ti < len(t) && qi < len(q); ti++ {
+		if t[ti] != q[qi] {
+			run = 0
+			continue
+		}
+		run++
+		score += run
+		if ti == 0 || !isWordRune(t[ti-1]) {
+			score += 3
+		}
+		qi++
+	}
+	if qi < len(q) {
+		return 0
+	}
+	return score
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
@@ -0,0 +1,20 @@
+package accessor
+
+import "github.com/Coop25/CC-Radio/metrics"
+
+var (
+	fetchDuration = metrics.NewHistogramVec("ccradio_fetch_duration_seconds",
+		"Latency of requests to the converter service.", "op", metrics.DefBuckets)
+	fetchFailures = metrics.NewCounterVec("ccradio_fetch_failures_total",
+		"Failed requests to the converter service.", "op")
+	fetchRetries = metrics.NewCounterVec("ccradio_fetch_retries_total",
+		"Converter requests retried after a failure.", "op")
+	converterUp = metrics.NewGauge("ccradio_converter_up",
+		"1 while the converter's circuit breaker is closed, 0 while it is open.")
+	cacheHits = metrics.NewCounter("ccradio_audio_cache_hits_total",
+		"Audio fetches served from the in-memory cache.")
+	cacheMisses = metrics.NewCounter("ccradio_audio_cache_misses_total",
+		"Audio fetches that had to go to the converter service.")
+	gistSaves = metrics.NewCounterVec("ccradio_gist_saves_total",
+		"Playlist saves to the Gist, by result.", "result")
+)
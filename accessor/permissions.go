@@ -0,0 +1,229 @@
+package accessor
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/Coop25/CC-Radio/config"
+)
+
+// Level is the minimum rank needed to run a command.
+type Level string
+
+const (
+	LevelEveryone Level = "everyone"
+	LevelDJ       Level = "dj"
+	LevelAdmin    Level = "admin"
+)
+
+// ParseLevel validates a level name from user input.
+func ParseLevel(s string) (Level, error) {
+	switch l := Level(s); l {
+	case LevelEveryone, LevelDJ, LevelAdmin:
+		return l, nil
+	}
+	return "", fmt.Errorf("unknown level %q (want everyone, dj or admin)", s)
+}
+
+// defaultLevels applies to commands that have no explicit entry.
+var defaultLevels = map[string]Level{
+	"skip":                 LevelDJ,
+	"force-radio-segment":  LevelDJ,
+	"addplaylist":          LevelDJ,
+	"add-radio-segment":    LevelDJ,
+	"tag":                  LevelDJ,
+	"untag":                LevelDJ,
+	"bulktag":              LevelDJ,
+	"rotation-filter":      LevelDJ,
+	"interrupt":            LevelDJ,
+	"announce":             LevelDJ,
+	"pause":                LevelDJ,
+	"resume":               LevelDJ,
+	"seek":                 LevelDJ,
+	"forward":              LevelDJ,
+	"rewind":               LevelDJ,
+	"replay":               LevelDJ,
+	"previous":             LevelDJ,
+	"deletecurrent":        LevelAdmin,
+	"removesong":           LevelAdmin,
+	"remove-radio-segment": LevelAdmin,
+	"removematching":       LevelAdmin,
+	"undo":                 LevelAdmin,
+	"saveplaylist":         LevelAdmin,
+	"permissions":          LevelAdmin,
+	"schedule-edit":        LevelAdmin,
+}
+
+// permissionsBackup is the snapshot format of permissions.json.
+type permissionsBackup struct {
+	DJRoles    []string         `json:"dj_roles"`
+	AdminRoles []string         `json:"admin_roles"`
+	Commands   map[string]Level `json:"commands"`
+}
+
+// Permissions maps Discord roles to ranks and commands to required ranks.
+type Permissions struct {
+	mu         sync.Mutex
+	djRoles    map[string]bool
+	adminRoles map[string]bool
+	commands   map[string]Level
+}
+
+// NewPermissions seeds the role lists from config; the Gist copy, if any, replaces them on load.
+func NewPermissions(cfg *config.Config) *Permissions {
+	p := &Permissions{
+		djRoles:    make(map[string]bool),
+		adminRoles: make(map[string]bool),
+		commands:   make(map[string]Level),
+	}
+	for _, r := range cfg.DiscordDJRoles {
+		p.djRoles[r] = true
+	}
+	for _, r := range cfg.DiscordAdminRoles {
+		p.adminRoles[r] = true
+	}
+	return p
+}
+
+// Required returns the level a command needs.
+func (p *Permissions) Required(command string) Level {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.required(command)
+}
+
+func (p *Permissions) required(command string) Level {
+	if l, ok := p.commands[command]; ok {
+		return l
+	}
+	if l, ok := defaultLevels[command]; ok {
+		return l
+	}
+	return LevelEveryone
+}
+
+// SetRequired overrides the level for one command.
+func (p *Permissions) SetRequired(command string, l Level) {
+	p.mu.Lock()
+	p.commands[command] = l
+	p.mu.Unlock()
+}
+
+// SetRole grants or revokes a rank for a role ID.
+func (p *Permissions) SetRole(l Level, roleID string, grant bool) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var set map[string]bool
+	switch l {
+	case LevelDJ:
+		set = p.djRoles
+	case LevelAdmin:
+		set = p.adminRoles
+	default:
+		return fmt.Errorf("roles can only be assigned to dj or admin")
+	}
+	if grant {
+		set[roleID] = true
+	} else {
+		delete(set, roleID)
+	}
+	return nil
+}
+
+// Allowed reports whether a member with these roles may run command.
+// isManager is true for members with Manage Server, who always count as admin.
+// With no DJ roles configured, DJ commands stay open to everyone.
+func (p *Permissions) Allowed(command string, roles []string, isManager bool) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	admin := isManager
+	dj := false
+	for _, r := range roles {
+		admin = admin || p.adminRoles[r]
+		dj = dj || p.djRoles[r]
+	}
+
+	switch p.required(command) {
+	case LevelAdmin:
+		return admin
+	case LevelDJ:
+		return admin || dj || len(p.djRoles) == 0
+	case LevelEveryone:
+		return true
+	default:
+		return admin // an unknown level fails closed
+	}
+}
+
+// PermissionsView is a read-only copy for display.
+type PermissionsView struct {
+	DJRoles    []string
+	AdminRoles []string
+	Commands   map[string]Level // effective level for every known command
+}
+
+// View returns the effective mapping for the given command names.
+func (p *Permissions) View(commands []string) PermissionsView {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	v := PermissionsView{
+		DJRoles:    sortedSet(p.djRoles),
+		AdminRoles: sortedSet(p.adminRoles),
+		Commands:   make(map[string]Level, len(commands)),
+	}
+	for _, c := range commands {
+		v.Commands[c] = p.required(c)
+	}
+	return v
+}
+
+func (p *Permissions) backup() permissionsBackup {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	cmds := make(map[string]Level, len(p.commands))
+	for k, v := range p.commands {
+		cmds[k] = v
+	}
+	return permissionsBackup{
+		DJRoles:    sortedSet(p.djRoles),
+		AdminRoles: sortedSet(p.adminRoles),
+		Commands:   cmds,
+	}
+}
+
+// restore replaces p with b, dropping command entries whose level isn't
+// valid so those commands fall back to their defaults; it returns the
+// commands it dropped.
+func (p *Permissions) restore(b permissionsBackup) (dropped []string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.djRoles = make(map[string]bool)
+	p.adminRoles = make(map[string]bool)
+	p.commands = make(map[string]Level)
+	for _, r := range b.DJRoles {
+		p.djRoles[r] = true
+	}
+	for _, r := range b.AdminRoles {
+		p.adminRoles[r] = true
+	}
+	for k, v := range b.Commands {
+		if _, err := ParseLevel(string(v)); err != nil {
+			dropped = append(dropped, k)
+			continue
+		}
+		p.commands[k] = v
+	}
+	sort.Strings(dropped)
+	return dropped
+}
+
+func sortedSet(m map[string]bool) []string {
+	out := make([]string, 0, len(m))
+	for k := range m {
+		out = append(out, k)
+	}
+	sort.Strings(out)
+	return out
+}
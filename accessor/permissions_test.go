@@ -0,0 +1,34 @@
+package accessor
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/Coop25/CC-Radio/config"
+)
+
+func TestUnknownLevelsFailClosed(t *testing.T) {
+	p := NewPermissions(&config.Config{})
+	dropped := p.restore(permissionsBackup{
+		AdminRoles: []string{"admin"},
+		Commands:   map[string]Level{"removesong": "evryone", "skip": LevelEveryone},
+	})
+	if want := []string{"removesong"}; !reflect.DeepEqual(dropped, want) {
+		t.Errorf("restore dropped %v, want %v", dropped, want)
+	}
+	if p.Allowed("removesong", nil, false) {
+		t.Error("a typo'd level opened an admin command to everyone")
+	}
+	if !p.Allowed("removesong", []string{"admin"}, false) {
+		t.Error("admin denied a command whose level was dropped")
+	}
+	if !p.Allowed("skip", nil, false) {
+		t.Error("valid level from the backup was not applied")
+	}
+
+	// a level that gets past restore anyway is admin-only
+	p.commands["skip"] = "bogus"
+	if p.Allowed("skip", nil, false) || !p.Allowed("skip", nil, true) {
+		t.Error("unknown level should allow admins only")
+	}
+}
@@ -0,0 +1,94 @@
+package accessor
+
+import "time"
+
+// lastPick remembers the playlist state just before the most recent Next,
+// so Requeue can undo it when the broadcaster discards its prefetched track.
+type lastPick struct {
+	song        Song
+	radio       bool
+	played      time.Time // lastPlayed/lastRadioPlayed entry before the pick
+	wasSegment  bool
+	ruleStates  []SegmentRule
+	forcedRadio bool
+}
+
+// Replan tells the broadcaster the upcoming plan changed, so it should put
+// back its prefetched next track and ask Next again.
+func (p *Playlist) Replan() {
+	select {
+	case p.PlanChangedCh <- struct{}{}:
+	default:
+	}
+}
+
+// Requeue undoes the most recent Next if it returned song: the song goes back
+// to the front of its deck and the segment counters are restored. A song that
+// has since been removed is dropped; anything else is a no-op.
+func (p *Playlist) Requeue(song Song) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	lp := p.pick
+	p.pick = nil
+	if lp == nil || lp.song.ID != song.ID {
+		return
+	}
+	p.lastWasSegment = lp.wasSegment
+	p.forceNextRadio = p.forceNextRadio || lp.forcedRadio
+	for n, r := range p.segmentRules {
+		if n < len(lp.ruleStates) {
+			r.lastFired = lp.ruleStates[n].lastFired
+			r.songsSince = lp.ruleStates[n].songsSince
+		}
+	}
+
+	list, deck, idx, played := p.queue, &p.shuffledQueue, &p.shuffledIndex, p.lastPlayed
+	if lp.radio {
+		list, deck, idx, played = p.randomNext, &p.shuffledRadio, &p.shuffledRadioIndex, p.lastRadioPlayed
+	}
+	if !containsSong(list, song.ID) {
+		// removed since; its play time went with it for /undo
+		return
+	}
+	if lp.played.IsZero() {
+		delete(played, song.ID)
+	} else {
+		played[song.ID] = lp.played
+	}
+	if *idx > 0 && *idx <= len(*deck) && (*deck)[*idx-1].ID == song.ID {
+		*idx--
+		return
+	}
+	// the deck was reshuffled or pruned since; put the song at the front of what's left
+	rest := append([]Song{song}, (*deck)[*idx:]...)
+	*deck, *idx = rest, 0
+}
+
+// rememberPick snapshots what Requeue needs before Next changes anything; caller holds p.mu.
+func (p *Playlist) rememberPick() *lastPick {
+	lp := &lastPick{
+		wasSegment:  p.lastWasSegment,
+		forcedRadio: p.forceNextRadio,
+		ruleStates:  make([]SegmentRule, len(p.segmentRules)),
+	}
+	for n, r := range p.segmentRules {
+		lp.ruleStates[n] = SegmentRule{lastFired: r.lastFired, songsSince: r.songsSince}
+	}
+	return lp
+}
+
+// finishPick records the song Next returned; caller holds p.mu.
+func (p *Playlist) finishPick(lp *lastPick, song Song, radio bool) {
+	lp.song, lp.radio, lp.played = song, radio, p.prevPlayed
+	p.pick = lp
+}
+
+func containsSong(list []Song, id string) bool {
+	for _, s := range list {
+		if s.ID == id {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,110 @@
+package accessor
+
+import "testing"
+
+func mustNext(t *testing.T, p *Playlist) Song {
+	t.Helper()
+	s, ok := p.Next()
+	if !ok {
+		t.Fatal("nothing to play")
+	}
+	return s
+}
+
+func TestRequeue(t *testing.T) {
+	p := newTestPlaylist(t, 5)
+	first := mustNext(t, p)
+	played := p.lastPlayed[first.ID]
+
+	second := mustNext(t, p)
+	p.Requeue(second)
+	if _, ok := p.lastPlayed[second.ID]; ok {
+		t.Errorf("requeued %s still has a play time", second.ID)
+	}
+	if again := mustNext(t, p); again.ID != second.ID {
+		t.Errorf("after requeueing %s Next gave %s", second.ID, again.ID)
+	}
+
+	// only the latest pick can be put back, and only once
+	p.Requeue(first)
+	if got := p.lastPlayed[first.ID]; !got.Equal(played) {
+		t.Errorf("requeueing an older pick changed its play time to %v", got)
+	}
+	p.Requeue(second)
+	p.Requeue(second)
+	seen := map[string]bool{second.ID: true}
+	for i := 0; i < 3; i++ {
+		s := mustNext(t, p)
+		if seen[s.ID] || s.ID == first.ID {
+			t.Fatalf("%s came round again within one deck", s.ID)
+		}
+		seen[s.ID] = true
+	}
+}
+
+func TestRequeueRestoresSegmentRules(t *testing.T) {
+	p := newSegmentPlaylist(t, `[{"every_songs": 2}]`, Song{ID: "jingle"})
+	mustNext(t, p)
+	s := mustNext(t, p)
+	p.Requeue(s)
+	if got := p.segmentRules[0].songsSince; got != 1 {
+		t.Fatalf("songsSince = %d after requeueing the second song, want 1", got)
+	}
+	mustNext(t, p)
+	seg := mustNext(t, p)
+	if seg.ID != "jingle" {
+		t.Fatalf("played %s after 2 songs, want the jingle", seg.ID)
+	}
+	p.Requeue(seg)
+	if got := p.segmentRules[0].songsSince; got != 2 || p.lastWasSegment {
+		t.Errorf("after requeueing the jingle songsSince = %d, lastWasSegment = %v; want 2, false", got, p.lastWasSegment)
+	}
+	if again := mustNext(t, p); again.ID != "jingle" {
+		t.Errorf("after requeueing the jingle Next gave %s", again.ID)
+	}
+}
+
+func TestRequeueRemovedSong(t *testing.T) {
+	p := newTestPlaylist(t, 3)
+	// into the second deck, so the pick had a play time before it
+	for i := 0; i < 3; i++ {
+		mustNext(t, p)
+	}
+	s := mustNext(t, p)
+	if _, err := p.RemoveSong(s.ID); err != nil {
+		t.Fatal(err)
+	}
+	p.Requeue(s)
+	if _, ok := p.lastPlayed[s.ID]; ok {
+		t.Errorf("requeueing removed %s gave it a play time", s.ID)
+	}
+	for i := 0; i < 4; i++ {
+		if got := mustNext(t, p); got.ID == s.ID {
+			t.Fatalf("removed %s played after being requeued", s.ID)
+		}
+	}
+
+	// /undo brings back the play time it had when removed
+	if _, err := p.Undo(); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := p.lastPlayed[s.ID]; !ok {
+		t.Errorf("undo did not restore %s's play time", s.ID)
+	}
+}
+
+func TestReplan(t *testing.T) {
+	p := newTestPlaylist(t, 1)
+	p.Replan()
+	p.Replan() // coalesces rather than blocking
+	select {
+	case <-p.PlanChangedCh:
+	default:
+		t.Fatal("Replan did not signal PlanChangedCh")
+	}
+	select {
+	case <-p.PlanChangedCh:
+		t.Error("two Replans signalled twice")
+	default:
+	}
+}
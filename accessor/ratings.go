@@ -0,0 +1,209 @@
+package accessor
+
+import (
+	"sort"
+	"sync"
+)
+
+// Ratings holds per-user likes/dislikes and favorites lists.
+type Ratings struct {
+	mu        sync.Mutex
+	votes     map[string]map[string]int // songID → userID → +1/-1
+	favorites map[string][]string       // userID → songIDs, most recent last
+	favCount  map[string]int            // songID → users who favorited it
+	dirty     bool
+}
+
+// ratingsBackup is the snapshot format of ratings.json.
+type ratingsBackup struct {
+	Votes     map[string]map[string]int `json:"votes"`
+	Favorites map[string][]string       `json:"favorites"`
+}
+
+func NewRatings() *Ratings {
+	return &Ratings{
+		votes:     make(map[string]map[string]int),
+		favorites: make(map[string][]string),
+		favCount:  make(map[string]int),
+	}
+}
+
+// Rate records user's opinion of a song: 1 like, -1 dislike, 0 clears it.
+// It returns the song's new aggregate score.
+func (r *Ratings) Rate(songID, user string, value int) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	switch {
+	case value > 0:
+		value = 1
+	case value < 0:
+		value = -1
+	}
+
+	users := r.votes[songID]
+	if value == 0 {
+		delete(users, user)
+		if len(users) == 0 {
+			delete(r.votes, songID)
+		}
+	} else {
+		if users == nil {
+			users = make(map[string]int)
+			r.votes[songID] = users
+		}
+		users[user] = value
+	}
+	r.dirty = true
+	return r.score(songID)
+}
+
+// Score is likes minus dislikes.
+func (r *Ratings) Score(songID string) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.score(songID)
+}
+
+// PlayScore is how the rotation weighs a song: its score plus one for each
+// user who has it as a favorite.
+func (r *Ratings) PlayScore(songID string) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.score(songID) + r.favCount[songID]
+}
+
+func (r *Ratings) score(songID string) int {
+	total := 0
+	for _, v := range r.votes[songID] {
+		total += v
+	}
+	return total
+}
+
+// Counts returns likes and dislikes for a song.
+func (r *Ratings) Counts(songID string) (likes, dislikes int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, v := range r.votes[songID] {
+		if v > 0 {
+			likes++
+		} else {
+			dislikes++
+		}
+	}
+	return likes, dislikes
+}
+
+// ToggleFavorite adds or removes songID from user's favorites; true means it is now a favorite.
+func (r *Ratings) ToggleFavorite(user, songID string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.dirty = true
+	favs := r.favorites[user]
+	for n, id := range favs {
+		if id == songID {
+			r.favorites[user] = append(favs[:n:n], favs[n+1:]...)
+			if len(r.favorites[user]) == 0 {
+				delete(r.favorites, user)
+			}
+			if r.favCount[songID]--; r.favCount[songID] <= 0 {
+				delete(r.favCount, songID)
+			}
+			return false
+		}
+	}
+	r.favorites[user] = append(favs, songID)
+	r.favCount[songID]++
+	return true
+}
+
+// Favorites returns user's favorite song IDs, most recent first.
+func (r *Ratings) Favorites(user string) []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	favs := r.favorites[user]
+	out := make([]string, len(favs))
+	for n, id := range favs {
+		out[len(favs)-1-n] = id
+	}
+	return out
+}
+
+// SongScore pairs a song ID with its aggregate score.
+type SongScore struct {
+	ID    string
+	Score int
+}
+
+// Top returns the n highest-scored songs (n <= 0 for all), best first.
+func (r *Ratings) Top(n int) []SongScore {
+	r.mu.Lock()
+	out := make([]SongScore, 0, len(r.votes))
+	for id := range r.votes {
+		out = append(out, SongScore{ID: id, Score: r.score(id)})
+	}
+	r.mu.Unlock()
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Score != out[j].Score {
+			return out[i].Score > out[j].Score
+		}
+		return out[i].ID < out[j].ID
+	})
+	if n > 0 && len(out) > n {
+		out = out[:n]
+	}
+	return out
+}
+
+func (r *Ratings) backup() ratingsBackup {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	b := ratingsBackup{
+		Votes:     make(map[string]map[string]int, len(r.votes)),
+		Favorites: make(map[string][]string, len(r.favorites)),
+	}
+	for id, users := range r.votes {
+		cp := make(map[string]int, len(users))
+		for u, v := range users {
+			cp[u] = v
+		}
+		b.Votes[id] = cp
+	}
+	for u, favs := range r.favorites {
+		b.Favorites[u] = append([]string(nil), favs...)
+	}
+	return b
+}
+
+func (r *Ratings) restore(b ratingsBackup) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.votes = make(map[string]map[string]int)
+	r.favorites = make(map[string][]string)
+	r.favCount = make(map[string]int)
+	for id, users := range b.Votes {
+		r.votes[id] = users
+	}
+	for u, favs := range b.Favorites {
+		r.favorites[u] = favs
+		for _, id := range favs {
+			r.favCount[id]++
+		}
+	}
+	r.dirty = false
+}
+
+// takeDirty reports whether there are unsaved changes and clears the flag.
+func (r *Ratings) takeDirty() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	d := r.dirty
+	r.dirty = false
+	return d
+}
+
+func (r *Ratings) markDirty() {
+	r.mu.Lock()
+	r.dirty = true
+	r.mu.Unlock()
+}
@@ -0,0 +1,239 @@
+package accessor
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Removal is one batch of songs taken out of the playlist, kept so /undo can put them back.
+type Removal struct {
+	Songs []Song
+	Radio []Song
+	At    time.Time
+
+	lastPlayed      map[string]time.Time
+	lastRadioPlayed map[string]time.Time
+}
+
+// Len is the number of songs and segments removed.
+func (r Removal) Len() int { return len(r.Songs) + len(r.Radio) }
+
+// RemoveSong removes one song from the master list by ID.
+func (p *Playlist) RemoveSong(id string) (Removal, error) {
+	return p.removeWhere(func(s Song) bool { return s.ID == id }, true, false, "song "+id)
+}
+
+// RemoveRadio removes one radio segment by ID.
+func (p *Playlist) RemoveRadio(id string) (Removal, error) {
+	return p.removeWhere(func(s Song) bool { return s.ID == id }, false, true, "radio segment "+id)
+}
+
+// Pattern is a compiled name/artist pattern; see ParsePattern.
+type Pattern struct {
+	re *regexp.Regexp
+}
+
+// ParsePattern compiles a case-insensitive glob (`*`, `?`, `[...]`) matched
+// against a song's name or artist. Unlike a file glob `/` is an ordinary
+// character, so `*dc*` matches "AC/DC". A pattern without wildcards matches
+// as a substring.
+func ParsePattern(pattern string) (*Pattern, error) {
+	if !strings.ContainsAny(pattern, "*?[") {
+		pattern = "*" + pattern + "*"
+	}
+	var b strings.Builder
+	b.WriteString("(?is)^")
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; c {
+		case '*':
+			b.WriteString(".*")
+		case '?':
+			b.WriteString(".")
+		case '[':
+			end := strings.IndexByte(pattern[i+1:], ']')
+			if end < 0 {
+				return nil, fmt.Errorf("pattern %q: unclosed [", pattern)
+			}
+			class := pattern[i+1 : i+1+end]
+			if class == "" || class == "!" || class == "^" {
+				return nil, fmt.Errorf("pattern %q: empty []", pattern)
+			}
+			if class[0] == '!' {
+				class = "^" + class[1:]
+			}
+			b.WriteString("[" + strings.ReplaceAll(class, `\`, `\\`) + "]")
+			i += end + 1
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	b.WriteString("$")
+	re, err := regexp.Compile(b.String())
+	if err != nil {
+		return nil, fmt.Errorf("pattern %q: invalid character class", pattern)
+	}
+	return &Pattern{re: re}, nil
+}
+
+// Matches reports whether the pattern matches the song's name or artist.
+func (p *Pattern) Matches(s Song) bool {
+	return p.re.MatchString(s.Name) || p.re.MatchString(s.Artist)
+}
+
+// Matching lists songs (or radio segments) a bulk removal with pattern would take.
+func (p *Playlist) Matching(pattern string, radio bool) ([]Song, error) {
+	pat, err := ParsePattern(pattern)
+	if err != nil {
+		return nil, err
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	list := p.queue
+	if radio {
+		list = p.randomNext
+	}
+	var out []Song
+	for _, s := range list {
+		if pat.Matches(s) {
+			out = append(out, s)
+		}
+	}
+	return out, nil
+}
+
+// RemoveMatching removes every song (or radio segment) matching pattern.
+func (p *Playlist) RemoveMatching(pattern string, radio bool) (Removal, error) {
+	if strings.Trim(pattern, "*? ") == "" {
+		return Removal{}, fmt.Errorf("pattern %q would match everything", pattern)
+	}
+	pat, err := ParsePattern(pattern)
+	if err != nil {
+		return Removal{}, err
+	}
+	return p.removeWhere(pat.Matches, !radio, radio, fmt.Sprintf("pattern %q", pattern))
+}
+
+// Undo restores the most recent removal if it happened within the undo window.
+func (p *Playlist) Undo() (Removal, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	r := p.lastRemoval
+	if r == nil {
+		return Removal{}, fmt.Errorf("nothing to undo")
+	}
+	if time.Since(r.At) > p.undoWindow {
+		p.lastRemoval = nil
+		return Removal{}, fmt.Errorf("last removal was %s ago; undo window is %s",
+			time.Since(r.At).Round(time.Second), p.undoWindow)
+	}
+	p.lastRemoval = nil
+
+	// songs re-added since the removal stay as they are
+	wasEmpty := len(p.queue) == 0 && len(p.randomNext) == 0
+	restored := Removal{At: r.At}
+	p.queue, restored.Songs = appendMissing(p.queue, r.Songs)
+	p.randomNext, restored.Radio = appendMissing(p.randomNext, r.Radio)
+	for _, s := range restored.Songs {
+		if t, ok := r.lastPlayed[s.ID]; ok {
+			p.lastPlayed[s.ID] = t
+		}
+	}
+	for _, s := range restored.Radio {
+		if t, ok := r.lastRadioPlayed[s.ID]; ok {
+			p.lastRadioPlayed[s.ID] = t
+		}
+	}
+	if restored.Len() == 0 {
+		return Removal{}, fmt.Errorf("everything in the last removal has been added back already")
+	}
+	if wasEmpty {
+		select {
+		case p.NewSongCh <- struct{}{}:
+		default:
+		}
+	}
+	p.Replan()
+	return restored, nil
+}
+
+// appendMissing adds the songs whose IDs aren't in list yet, returning the
+// new list and the songs it added.
+func appendMissing(list, add []Song) ([]Song, []Song) {
+	have := make(map[string]bool, len(list))
+	for _, s := range list {
+		have[s.ID] = true
+	}
+	var added []Song
+	for _, s := range add {
+		if !have[s.ID] {
+			have[s.ID] = true
+			added = append(added, s)
+		}
+	}
+	return append(list, added...), added
+}
+
+// removeWhere drops matching songs from the selected lists and from the
+// shuffled decks, remembering them for Undo. what describes the target in errors.
+func (p *Playlist) removeWhere(match func(Song) bool, fromQueue, fromRadio bool, what string) (Removal, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	r := Removal{
+		At:              time.Now(),
+		lastPlayed:      make(map[string]time.Time),
+		lastRadioPlayed: make(map[string]time.Time),
+	}
+	if fromQueue {
+		p.queue, r.Songs = partition(p.queue, match)
+		for _, s := range r.Songs {
+			if t, ok := p.lastPlayed[s.ID]; ok {
+				r.lastPlayed[s.ID] = t
+				delete(p.lastPlayed, s.ID)
+			}
+		}
+		p.shuffledQueue, p.shuffledIndex = pruneDeck(p.shuffledQueue, p.shuffledIndex, match)
+	}
+	if fromRadio {
+		p.randomNext, r.Radio = partition(p.randomNext, match)
+		for _, s := range r.Radio {
+			if t, ok := p.lastRadioPlayed[s.ID]; ok {
+				r.lastRadioPlayed[s.ID] = t
+				delete(p.lastRadioPlayed, s.ID)
+			}
+		}
+		p.shuffledRadio, p.shuffledRadioIndex = pruneDeck(p.shuffledRadio, p.shuffledRadioIndex, match)
+	}
+
+	if r.Len() == 0 {
+		return r, fmt.Errorf("no match for %s", what)
+	}
+	p.lastRemoval = &r
+	p.Replan()
+	return r, nil
+}
+
+// partition splits list into the songs to keep and the ones match selects.
+func partition(list []Song, match func(Song) bool) (keep, removed []Song) {
+	keep = make([]Song, 0, len(list))
+	for _, s := range list {
+		if match(s) {
+			removed = append(removed, s)
+		} else {
+			keep = append(keep, s)
+		}
+	}
+	return keep, removed
+}
+
+// pruneDeck drops matching songs from the unplayed part of a shuffled deck.
+func pruneDeck(deck []Song, idx int, match func(Song) bool) ([]Song, int) {
+	if deck == nil || idx >= len(deck) {
+		return deck, idx
+	}
+	rest, _ := partition(deck[idx:], match)
+	return rest, 0
+}
@@ -0,0 +1,305 @@
+package accessor
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Block is one recurring programming window, e.g. weekdays 18:00–20:00 "Rock hour".
+// While it is on air Playlist.Next only picks master-list songs it selects.
+type Block struct {
+	Name  string `json:"name"`
+	Days  string `json:"days"`  // "daily", "weekdays", "weekends", "mon-fri", "sat,sun", …
+	Start string `json:"start"` // "HH:MM" in the schedule's timezone
+	End   string `json:"end"`   // "HH:MM"; earlier than Start means it runs past midnight
+
+	Songs   []string `json:"songs,omitempty"`   // explicit song IDs
+	Pattern string   `json:"pattern,omitempty"` // name/artist pattern, as for /removematching
+	Tags    string   `json:"tags,omitempty"`    // tag expression, e.g. "rock and not live"
+
+	// RadioCooldownMinutes overrides the cooldown of every cooldown-based radio
+	// rule while the block is on; 0 keeps them.
+	RadioCooldownMinutes int `json:"radio_cooldown_minutes,omitempty"`
+
+	days       [7]bool
+	start, end int // minutes after midnight
+	songSet    map[string]bool
+	pattern    *Pattern
+	tagExpr    *TagExpr
+}
+
+var dayNames = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+// compile validates the block and fills in its parsed fields.
+func (b *Block) compile() error {
+	if strings.TrimSpace(b.Name) == "" {
+		return fmt.Errorf("block needs a name")
+	}
+	days, err := parseDays(b.Days)
+	if err != nil {
+		return fmt.Errorf("block %q: %w", b.Name, err)
+	}
+	start, err := parseClock(b.Start)
+	if err != nil {
+		return fmt.Errorf("block %q start: %w", b.Name, err)
+	}
+	end, err := parseClock(b.End)
+	if err != nil {
+		return fmt.Errorf("block %q end: %w", b.Name, err)
+	}
+	if start == end {
+		return fmt.Errorf("block %q starts and ends at the same time", b.Name)
+	}
+	b.days, b.start, b.end = days, start, end
+	b.pattern = nil
+	if b.Pattern != "" {
+		if b.pattern, err = ParsePattern(b.Pattern); err != nil {
+			return fmt.Errorf("block %q: %w", b.Name, err)
+		}
+	}
+	b.tagExpr = nil
+	if strings.TrimSpace(b.Tags) != "" {
+		if b.tagExpr, err = ParseTagExpr(b.Tags); err != nil {
+			return fmt.Errorf("block %q tags: %w", b.Name, err)
+		}
+	}
+	b.songSet = make(map[string]bool, len(b.Songs))
+	for _, id := range b.Songs {
+		b.songSet[id] = true
+	}
+	return nil
+}
+
+// Selects reports whether s belongs to the block's pool. Explicit songs win;
+// otherwise the pattern and tag expression must both match where set.
+func (b *Block) Selects(s Song) bool {
+	if len(b.songSet) > 0 {
+		return b.songSet[s.ID]
+	}
+	if b.pattern != nil && !b.pattern.Matches(s) {
+		return false
+	}
+	return b.tagExpr == nil || b.tagExpr.Matches(s)
+}
+
+// RadioCooldown is the block's cooldown override, or 0.
+func (b *Block) RadioCooldown() time.Duration {
+	return time.Duration(b.RadioCooldownMinutes) * time.Minute
+}
+
+// activeAt reports whether the block is on air at t (already in the schedule's zone).
+func (b *Block) activeAt(t time.Time) bool {
+	m := t.Hour()*60 + t.Minute()
+	today := t.Weekday()
+	if b.start < b.end {
+		return b.days[today] && m >= b.start && m < b.end
+	}
+	yesterday := (today + 6) % 7
+	return (b.days[today] && m >= b.start) || (b.days[yesterday] && m < b.end)
+}
+
+// nextStart is the first time strictly after t that the block begins.
+// Start is built from the wall clock, not added to midnight, so it stays
+// right on days the clocks change. A start the clocks skip over begins
+// when they land.
+func (b *Block) nextStart(t time.Time) time.Time {
+	y, m, d := t.Date()
+	for i := 0; i <= 7; i++ {
+		st := time.Date(y, m, d+i, b.start/60, b.start%60, 0, 0, t.Location())
+		if st.Hour()*60+st.Minute() != b.start {
+			// time.Date put it before the jump; the block starts right after
+			_, st = st.ZoneBounds()
+		}
+		if b.days[st.Weekday()] && st.After(t) {
+			return st
+		}
+	}
+	return time.Time{}
+}
+
+// Schedule is the set of programming blocks and the timezone they are written in.
+type Schedule struct {
+	mu     sync.Mutex
+	loc    *time.Location
+	blocks []Block
+}
+
+// NewSchedule parses the timezone and the optional JSON block list from config.
+func NewSchedule(timezone, blocksJSON string) (*Schedule, error) {
+	loc, err := time.LoadLocation(timezone)
+	if err != nil {
+		return nil, fmt.Errorf("invalid TIMEZONE %q: %w", timezone, err)
+	}
+	s := &Schedule{loc: loc}
+	if strings.TrimSpace(blocksJSON) == "" {
+		return s, nil
+	}
+	var blocks []Block
+	if err := json.Unmarshal([]byte(blocksJSON), &blocks); err != nil {
+		return nil, fmt.Errorf("invalid SCHEDULE JSON: %w", err)
+	}
+	if err := s.replace(blocks); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Location is the timezone block times are interpreted in.
+func (s *Schedule) Location() *time.Location {
+	return s.loc
+}
+
+// Active returns the block on air at t, if any. Earlier blocks win overlaps.
+func (s *Schedule) Active(t time.Time) (Block, bool) {
+	if s == nil {
+		return Block{}, false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t = t.In(s.loc)
+	for _, b := range s.blocks {
+		if b.activeAt(t) {
+			return b, true
+		}
+	}
+	return Block{}, false
+}
+
+// Upcoming lists every block with its next start after t, soonest first.
+func (s *Schedule) Upcoming(t time.Time) []ScheduledBlock {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t = t.In(s.loc)
+	out := make([]ScheduledBlock, 0, len(s.blocks))
+	for _, b := range s.blocks {
+		if st := b.nextStart(t); !st.IsZero() {
+			out = append(out, ScheduledBlock{Block: b, StartsAt: st})
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].StartsAt.Before(out[j].StartsAt) })
+	return out
+}
+
+// ScheduledBlock is a block paired with its next start.
+type ScheduledBlock struct {
+	Block
+	StartsAt time.Time
+}
+
+// Blocks returns a copy of every block in order.
+func (s *Schedule) Blocks() []Block {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Block(nil), s.blocks...)
+}
+
+// Put adds a block, replacing any existing block with the same name.
+func (s *Schedule) Put(b Block) error {
+	if err := b.compile(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for n := range s.blocks {
+		if strings.EqualFold(s.blocks[n].Name, b.Name) {
+			s.blocks[n] = b
+			return nil
+		}
+	}
+	s.blocks = append(s.blocks, b)
+	return nil
+}
+
+// Delete removes the block called name.
+func (s *Schedule) Delete(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for n := range s.blocks {
+		if strings.EqualFold(s.blocks[n].Name, name) {
+			s.blocks = append(s.blocks[:n], s.blocks[n+1:]...)
+			return nil
+		}
+	}
+	return fmt.Errorf("no block named %q", name)
+}
+
+// scheduleBackup is the schedule.json format.
+type scheduleBackup struct {
+	Blocks []Block `json:"blocks"`
+}
+
+func (s *Schedule) backup() scheduleBackup {
+	return scheduleBackup{Blocks: s.Blocks()}
+}
+
+func (s *Schedule) replace(blocks []Block) error {
+	for n := range blocks {
+		if err := blocks[n].compile(); err != nil {
+			return err
+		}
+	}
+	s.mu.Lock()
+	s.blocks = blocks
+	s.mu.Unlock()
+	return nil
+}
+
+// parseDays turns "daily", "weekdays", "weekends" or a comma list of days
+// and ranges ("mon-fri,sun") into a weekday set.
+func parseDays(spec string) ([7]bool, error) {
+	var days [7]bool
+	spec = strings.ToLower(strings.TrimSpace(spec))
+	switch spec {
+	case "", "daily", "every day":
+		spec = "sun-sat"
+	case "weekdays":
+		spec = "mon-fri"
+	case "weekends":
+		spec = "sat,sun"
+	}
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		from, to, isRange := strings.Cut(part, "-")
+		a, ok := dayNames[shortDay(from)]
+		if !ok {
+			return days, fmt.Errorf("unknown day %q", from)
+		}
+		b := a
+		if isRange {
+			if b, ok = dayNames[shortDay(to)]; !ok {
+				return days, fmt.Errorf("unknown day %q", to)
+			}
+		}
+		for d := a; ; d = (d + 1) % 7 {
+			days[d] = true
+			if d == b {
+				break
+			}
+		}
+	}
+	return days, nil
+}
+
+func shortDay(s string) string {
+	s = strings.TrimSpace(s)
+	if len(s) > 3 {
+		s = s[:3]
+	}
+	return s
+}
+
+// parseClock turns "HH:MM" into minutes after midnight.
+func parseClock(s string) (int, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(s))
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q (want HH:MM)", s)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
@@ -0,0 +1,103 @@
+package accessor
+
+import (
+	"testing"
+	"time"
+)
+
+// newYork is a zone with clock changes: 2026-03-08 02:00 jumps to 03:00 and
+// 2026-11-01 02:00 falls back to 01:00.
+func newYork(t *testing.T) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("no tz database: %v", err)
+	}
+	return loc
+}
+
+func compiled(t *testing.T, b Block) *Block {
+	t.Helper()
+	if err := b.compile(); err != nil {
+		t.Fatal(err)
+	}
+	return &b
+}
+
+func TestActiveAtAcrossDST(t *testing.T) {
+	ny := newYork(t)
+	at := func(month time.Month, day, hour, min int) time.Time {
+		return time.Date(2026, month, day, hour, min, 0, 0, ny)
+	}
+	// the second 01:15 of the fall-back night
+	repeated := at(time.November, 1, 1, 15).Add(time.Hour)
+	if repeated.Hour() != 1 {
+		t.Fatalf("%v is not the repeated hour", repeated)
+	}
+
+	for _, tc := range []struct {
+		name  string
+		block Block
+		at    time.Time
+		want  bool
+	}{
+		{"before the spring gap", Block{Days: "daily", Start: "01:30", End: "03:30"}, at(time.March, 8, 1, 45), true},
+		{"after the spring gap", Block{Days: "daily", Start: "01:30", End: "03:30"}, at(time.March, 8, 3, 15), true},
+		{"past the end after the spring gap", Block{Days: "daily", Start: "01:30", End: "03:30"}, at(time.March, 8, 3, 45), false},
+		{"overnight into the spring gap", Block{Days: "sat", Start: "22:00", End: "02:30"}, at(time.March, 8, 1, 59), true},
+		{"overnight past the spring gap", Block{Days: "sat", Start: "22:00", End: "02:30"}, at(time.March, 8, 3, 0), false},
+		{"first pass of the repeated hour", Block{Days: "sun", Start: "01:00", End: "01:30"}, at(time.November, 1, 1, 15), true},
+		{"second pass of the repeated hour", Block{Days: "sun", Start: "01:00", End: "01:30"}, repeated, true},
+		{"overnight through the repeated hour", Block{Days: "sat", Start: "23:00", End: "01:30"}, repeated, true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.block.Name = "test"
+			if got := compiled(t, tc.block).activeAt(tc.at); got != tc.want {
+				t.Errorf("activeAt(%v) = %v, want %v", tc.at, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestNextStartAcrossDST(t *testing.T) {
+	ny := newYork(t)
+	for _, tc := range []struct {
+		name  string
+		block Block
+		from  time.Time
+		want  time.Time
+	}{
+		{
+			"spring forward",
+			Block{Days: "daily", Start: "09:00", End: "10:00"},
+			time.Date(2026, time.March, 7, 12, 0, 0, 0, ny),
+			time.Date(2026, time.March, 8, 9, 0, 0, 0, ny), // 20h later
+		},
+		{
+			"fall back",
+			Block{Days: "daily", Start: "09:00", End: "10:00"},
+			time.Date(2026, time.October, 31, 12, 0, 0, 0, ny),
+			time.Date(2026, time.November, 1, 9, 0, 0, 0, ny), // 22h later
+		},
+		{
+			"skips days off across the change",
+			Block{Days: "mon-fri", Start: "18:00", End: "20:00"},
+			time.Date(2026, time.March, 6, 19, 0, 0, 0, ny), // Friday, on air
+			time.Date(2026, time.March, 9, 18, 0, 0, 0, ny),
+		},
+		{
+			"start in the spring gap",
+			Block{Days: "daily", Start: "02:30", End: "04:00"},
+			time.Date(2026, time.March, 7, 12, 0, 0, 0, ny),
+			time.Date(2026, time.March, 8, 3, 0, 0, 0, ny), // 02:30 never happens; it is on from 03:00
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.block.Name = "test"
+			got := compiled(t, tc.block).nextStart(tc.from)
+			if !got.Equal(tc.want) {
+				t.Errorf("nextStart(%v) = %v, want %v", tc.from, got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,178 @@
+package accessor
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"strings"
+	"time"
+)
+
+// topOfHourWindow is how late into the hour a top-of-hour rule may still fire.
+const topOfHourWindow = 15 * time.Minute
+
+// SegmentRule decides when a category of radio segments plays. A segment's
+// category is one of its tags, so `/tag <segment> jingle` puts it in "jingle".
+// The triggers are alternatives: whichever is due first fires the rule.
+type SegmentRule struct {
+	Category string `json:"category,omitempty"` // tag segments must carry; "" means any segment
+
+	EverySongs      int     `json:"every_songs,omitempty"`      // after this many master songs
+	CooldownMinutes int     `json:"cooldown_minutes,omitempty"` // certain once this long has passed…
+	MaxChance       float64 `json:"max_chance,omitempty"`       // …and ramping up to this chance per song before then
+	TopOfHour       bool    `json:"top_of_hour,omitempty"`      // first break after the hour, e.g. station IDs
+
+	cooldown   time.Duration
+	lastFired  time.Time
+	songsSince int
+}
+
+// ParseSegmentRules reads RADIO_RULES, a JSON list of rules in priority order.
+func ParseSegmentRules(rulesJSON string) ([]*SegmentRule, error) {
+	var rules []*SegmentRule
+	if err := json.Unmarshal([]byte(rulesJSON), &rules); err != nil {
+		return nil, fmt.Errorf("invalid RADIO_RULES JSON: %w", err)
+	}
+	for n, r := range rules {
+		r.Category = NormalizeTag(r.Category)
+		r.cooldown = time.Duration(r.CooldownMinutes) * time.Minute
+		if r.EverySongs <= 0 && r.cooldown <= 0 && !r.TopOfHour {
+			return nil, fmt.Errorf("radio rule %d (%q) has no trigger", n, r.Category)
+		}
+		if r.MaxChance < 0 || r.MaxChance > 1 {
+			return nil, fmt.Errorf("radio rule %d (%q): max_chance must be between 0 and 1", n, r.Category)
+		}
+	}
+	return rules, nil
+}
+
+// defaultSegmentRules keeps the RANDOM_COOLDOWN behaviour for stations without
+// RADIO_RULES, with RANDOM_MAX_CHANCE allowing an early segment.
+func defaultSegmentRules(cooldown time.Duration, maxChance float64) []*SegmentRule {
+	return []*SegmentRule{{cooldown: cooldown, MaxChance: maxChance}}
+}
+
+func (r *SegmentRule) selects(s Song) bool {
+	return r.Category == "" || s.HasTag(r.Category)
+}
+
+// due reports whether the rule should fire before the next master song.
+// cooldownOverride, if set, replaces the rule's cooldown (programming blocks).
+func (r *SegmentRule) due(now time.Time, loc *time.Location, cooldownOverride time.Duration, rng *rand.Rand) bool {
+	if r.TopOfHour {
+		t := now.In(loc)
+		hour := time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, loc)
+		if t.Sub(hour) < topOfHourWindow && r.lastFired.Before(hour) {
+			return true
+		}
+	}
+	if r.EverySongs > 0 && r.songsSince >= r.EverySongs {
+		return true
+	}
+	cooldown := r.cooldown
+	if cooldown > 0 && cooldownOverride > 0 {
+		cooldown = cooldownOverride
+	}
+	if cooldown <= 0 {
+		return false
+	}
+	elapsed := now.Sub(r.lastFired)
+	if elapsed >= cooldown {
+		return true
+	}
+	// chance climbs linearly from 0 right after the last segment to MaxChance
+	return r.MaxChance > 0 && rng.Float64() < r.MaxChance*float64(elapsed)/float64(cooldown)
+}
+
+func (r *SegmentRule) fired(now time.Time) {
+	r.lastFired = now
+	r.songsSince = 0
+}
+
+// SetSegmentRules replaces the radio-segment rules.
+func (p *Playlist) SetSegmentRules(rules []*SegmentRule) {
+	p.mu.Lock()
+	p.segmentRules = rules
+	p.mu.Unlock()
+}
+
+// nextSegment plays the segment of the first due rule that has one.
+// Segments never play back to back; caller holds p.mu.
+func (p *Playlist) nextSegment(now time.Time, cooldownOverride time.Duration) (Song, bool) {
+	if p.lastWasSegment || len(p.randomNext) == 0 {
+		return Song{}, false
+	}
+	loc := time.Local
+	if p.schedule != nil {
+		loc = p.schedule.Location()
+	}
+	for _, r := range p.segmentRules {
+		if !r.due(now, loc, cooldownOverride, p.rng) {
+			continue
+		}
+		if song, ok := p.popRadioFiltered(now, r.selects); ok {
+			r.fired(now)
+			return song, true
+		}
+	}
+	return Song{}, false
+}
+
+// countSong advances the every-N-songs rules after a master song; caller holds p.mu.
+func (p *Playlist) countSong() {
+	for _, r := range p.segmentRules {
+		r.songsSince++
+	}
+}
+
+// popRadioFiltered is popFiltered for the radio deck.
+func (p *Playlist) popRadioFiltered(now time.Time, match func(Song) bool) (Song, bool) {
+	for attempt := 0; attempt < 2; attempt++ {
+		if p.shuffledRadio == nil || p.shuffledRadioIndex >= len(p.shuffledRadio) {
+			p.refillShuffledRadio(now)
+		}
+		for i := p.shuffledRadioIndex; i < len(p.shuffledRadio); i++ {
+			if !match(p.shuffledRadio[i]) {
+				continue
+			}
+			d := p.shuffledRadio
+			d[p.shuffledRadioIndex], d[i] = d[i], d[p.shuffledRadioIndex]
+			return p.popShuffledRadio(now)
+		}
+		p.shuffledRadioIndex = len(p.shuffledRadio)
+	}
+	return Song{}, false
+}
+
+// SegmentRules describes the active rules for display.
+func (p *Playlist) SegmentRules() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	out := make([]string, 0, len(p.segmentRules))
+	for _, r := range p.segmentRules {
+		out = append(out, r.String())
+	}
+	return out
+}
+
+func (r *SegmentRule) String() string {
+	what := "any segment"
+	if r.Category != "" {
+		what = "#" + r.Category
+	}
+	var when []string
+	if r.TopOfHour {
+		when = append(when, "top of the hour")
+	}
+	if r.EverySongs > 0 {
+		when = append(when, fmt.Sprintf("every %d songs", r.EverySongs))
+	}
+	if r.cooldown > 0 {
+		c := fmt.Sprintf("every %s", r.cooldown)
+		if r.MaxChance > 0 {
+			c += fmt.Sprintf(" (up to %.0f%% chance sooner)", r.MaxChance*100)
+		}
+		when = append(when, c)
+	}
+	return what + ": " + strings.Join(when, " or ")
+}
@@ -0,0 +1,132 @@
+package accessor
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+)
+
+// newSegmentPlaylist is a test playlist with the given RADIO_RULES and segments.
+func newSegmentPlaylist(t *testing.T, rulesJSON string, segments ...Song) *Playlist {
+	t.Helper()
+	p := newTestPlaylist(t, 3)
+	rules, err := ParseSegmentRules(rulesJSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.SetSegmentRules(rules)
+	for _, s := range segments {
+		p.AddRadio(s)
+	}
+	return p
+}
+
+// segmentAt is what nextSegment plays at now, or "" for nothing.
+func segmentAt(p *Playlist, now time.Time, cooldownOverride time.Duration) string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	s, ok := p.nextSegment(now, cooldownOverride)
+	if !ok {
+		return ""
+	}
+	return s.ID
+}
+
+func TestCooldownRule(t *testing.T) {
+	p := newSegmentPlaylist(t, `[{"cooldown_minutes": 10}]`, Song{ID: "jingle"})
+	t0 := time.Date(2026, time.May, 4, 12, 30, 0, 0, time.UTC)
+
+	for _, step := range []struct {
+		at       time.Duration
+		override time.Duration
+		want     string
+	}{
+		{0, 0, "jingle"}, // never fired, so long overdue
+		{5 * time.Minute, 0, ""},
+		{10*time.Minute - time.Second, 0, ""},
+		{10 * time.Minute, 0, "jingle"},
+		{13 * time.Minute, 0, ""},
+		{13 * time.Minute, 2 * time.Minute, "jingle"}, // a block's shorter cooldown
+		{14 * time.Minute, 2 * time.Minute, ""},
+	} {
+		if got := segmentAt(p, t0.Add(step.at), step.override); got != step.want {
+			t.Fatalf("at +%s (override %s): played %q, want %q", step.at, step.override, got, step.want)
+		}
+	}
+}
+
+func TestCooldownRuleRamp(t *testing.T) {
+	rules, err := ParseSegmentRules(`[{"cooldown_minutes": 10, "max_chance": 0.8}]`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := rules[0]
+	t0 := time.Date(2026, time.May, 4, 12, 0, 0, 0, time.UTC)
+	r.fired(t0)
+	rng := rand.New(rand.NewSource(1))
+
+	// halfway through the cooldown the chance is half of max_chance
+	due := 0
+	for i := 0; i < 10000; i++ {
+		if r.due(t0.Add(5*time.Minute), time.UTC, 0, rng) {
+			due++
+		}
+	}
+	if due < 3600 || due > 4400 {
+		t.Errorf("due %d times in 10000 halfway through the cooldown, want about 4000", due)
+	}
+	if r.due(t0, time.UTC, 0, rng) {
+		t.Error("due straight after firing")
+	}
+}
+
+func TestTopOfHourRule(t *testing.T) {
+	p := newSegmentPlaylist(t, `[{"category": "station-id", "top_of_hour": true}]`,
+		Song{ID: "id", Tags: []string{"station-id"}},
+		Song{ID: "jingle", Tags: []string{"jingle"}},
+	)
+	at := func(hour, min int) time.Time {
+		return time.Date(2026, time.May, 4, hour, min, 0, 0, time.UTC)
+	}
+	for _, step := range []struct {
+		at   time.Time
+		want string
+	}{
+		{at(12, 5), "id"},
+		{at(12, 10), ""}, // once per hour
+		{at(13, 0), "id"},
+		{at(14, 15), ""}, // past the window
+		{at(14, 59), ""},
+		{at(15, 14), "id"},
+	} {
+		if got := segmentAt(p, step.at, 0); got != step.want {
+			t.Fatalf("at %s: played %q, want %q", step.at.Format("15:04"), got, step.want)
+		}
+	}
+}
+
+func TestSegmentRulePriority(t *testing.T) {
+	p := newSegmentPlaylist(t, `[
+		{"category": "station-id", "top_of_hour": true},
+		{"category": "jingle", "every_songs": 2}
+	]`,
+		Song{ID: "id", Tags: []string{"station-id"}},
+		Song{ID: "jingle", Tags: []string{"jingle"}},
+	)
+	p.countSong()
+	p.countSong()
+	// both are due; the first rule wins and the second waits its turn
+	if got := segmentAt(p, time.Date(2026, time.May, 4, 12, 1, 0, 0, time.UTC), 0); got != "id" {
+		t.Fatalf("played %q, want the top-of-hour id", got)
+	}
+	if got := segmentAt(p, time.Date(2026, time.May, 4, 12, 5, 0, 0, time.UTC), 0); got != "jingle" {
+		t.Fatalf("played %q, want the jingle still due after 2 songs", got)
+	}
+
+	p.lastWasSegment = true
+	p.countSong()
+	p.countSong()
+	if got := segmentAt(p, time.Date(2026, time.May, 4, 13, 1, 0, 0, time.UTC), 0); got != "" {
+		t.Errorf("played %q right after a segment, want nothing", got)
+	}
+}
@@ -0,0 +1,305 @@
+package accessor
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// NormalizeTag lowercases a tag and turns inner spaces into dashes, so
+// "Lo Fi" and "lo-fi" are the same tag.
+func NormalizeTag(t string) string {
+	return strings.Join(strings.Fields(strings.ToLower(t)), "-")
+}
+
+// ParseTags splits a comma-separated tag list, dropping blanks and repeats.
+func ParseTags(list string) []string {
+	var out []string
+	seen := make(map[string]bool)
+	for _, t := range strings.Split(list, ",") {
+		t = NormalizeTag(t)
+		if t == "" || seen[t] {
+			continue
+		}
+		seen[t] = true
+		out = append(out, t)
+	}
+	return out
+}
+
+// HasTag reports whether s carries tag (already normalized).
+func (s Song) HasTag(tag string) bool {
+	return containsTag(s.Tags, tag)
+}
+
+func containsTag(tags []string, tag string) bool {
+	for _, t := range tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// TagExpr is a parsed tag expression such as "rock and not live" or
+// "(jazz or blues) chill". Juxtaposition means and; "-tag" and "!tag" mean not;
+// "," and "|" mean or.
+type TagExpr struct {
+	src  string
+	eval func(Song) bool
+}
+
+// ParseTagExpr parses a tag expression; an empty one is an error.
+func ParseTagExpr(src string) (*TagExpr, error) {
+	p := &tagParser{toks: tokenizeTags(src)}
+	if len(p.toks) == 0 {
+		return nil, fmt.Errorf("empty tag expression")
+	}
+	eval, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.toks) {
+		return nil, fmt.Errorf("unexpected %q in tag expression", p.toks[p.pos])
+	}
+	return &TagExpr{src: strings.TrimSpace(src), eval: eval}, nil
+}
+
+// Matches reports whether s satisfies the expression.
+func (e *TagExpr) Matches(s Song) bool { return e.eval(s) }
+
+func (e *TagExpr) String() string { return e.src }
+
+func tokenizeTags(src string) []string {
+	var toks []string
+	var cur strings.Builder
+	flush := func() {
+		if cur.Len() > 0 {
+			toks = append(toks, cur.String())
+			cur.Reset()
+		}
+	}
+	for _, r := range strings.ToLower(src) {
+		switch {
+		case unicode.IsSpace(r):
+			flush()
+		case strings.ContainsRune("()&|,!", r):
+			flush()
+			toks = append(toks, string(r))
+		case r == '-' && cur.Len() == 0:
+			toks = append(toks, "!")
+		default:
+			cur.WriteRune(r)
+		}
+	}
+	flush()
+	return toks
+}
+
+type tagParser struct {
+	toks []string
+	pos  int
+}
+
+func (p *tagParser) peek() string {
+	if p.pos < len(p.toks) {
+		return p.toks[p.pos]
+	}
+	return ""
+}
+
+func (p *tagParser) or() (func(Song) bool, error) {
+	left, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+	for t := p.peek(); t == "or" || t == "|" || t == ","; t = p.peek() {
+		p.pos++
+		right, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		l := left
+		left = func(s Song) bool { return l(s) || right(s) }
+	}
+	return left, nil
+}
+
+func (p *tagParser) and() (func(Song) bool, error) {
+	left, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		t := p.peek()
+		if t == "and" || t == "&" {
+			p.pos++
+		} else if t == "" || t == ")" || t == "or" || t == "|" || t == "," {
+			return left, nil
+		}
+		right, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		l := left
+		left = func(s Song) bool { return l(s) && right(s) }
+	}
+}
+
+func (p *tagParser) unary() (func(Song) bool, error) {
+	t := p.peek()
+	p.pos++
+	switch t {
+	case "":
+		return nil, fmt.Errorf("tag expression ends too early")
+	case "not", "!":
+		inner, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return func(s Song) bool { return !inner(s) }, nil
+	case "(":
+		inner, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		if p.peek() != ")" {
+			return nil, fmt.Errorf("missing ) in tag expression")
+		}
+		p.pos++
+		return inner, nil
+	case ")", "and", "&", "or", "|", ",":
+		return nil, fmt.Errorf("unexpected %q in tag expression", t)
+	}
+	return func(s Song) bool { return s.HasTag(t) }, nil
+}
+
+// Tag adds tags to the song or radio segment with id.
+func (p *Playlist) Tag(id string, tags []string) (Song, error) {
+	return p.retagOne(id, func(s *Song) { s.Tags = addTags(s.Tags, tags) })
+}
+
+// Untag removes tags from the song or radio segment with id.
+func (p *Playlist) Untag(id string, tags []string) (Song, error) {
+	return p.retagOne(id, func(s *Song) { s.Tags = dropTags(s.Tags, tags) })
+}
+
+// BulkTag adds tags to every master-list song whose name or artist matches pattern.
+func (p *Playlist) BulkTag(pattern string, tags []string) (int, error) {
+	pat, err := ParsePattern(pattern)
+	if err != nil {
+		return 0, err
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.updateSongs(pat.Matches, false, func(s *Song) {
+		s.Tags = addTags(s.Tags, tags)
+	}), nil
+}
+
+// TagCounts returns how many songs and segments carry each tag.
+func (p *Playlist) TagCounts() map[string]int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	counts := make(map[string]int)
+	for _, list := range [][]Song{p.queue, p.randomNext} {
+		for _, s := range list {
+			for _, t := range s.Tags {
+				counts[t]++
+			}
+		}
+	}
+	return counts
+}
+
+// SetRotationFilter limits the master rotation to songs matching expr;
+// an empty expr clears it.
+func (p *Playlist) SetRotationFilter(expr string) error {
+	var e *TagExpr
+	if strings.TrimSpace(expr) != "" {
+		var err error
+		if e, err = ParseTagExpr(expr); err != nil {
+			return err
+		}
+	}
+	p.mu.Lock()
+	p.rotation = e
+	p.mu.Unlock()
+	p.Replan()
+	return nil
+}
+
+// RotationFilter is the active rotation filter, or "".
+func (p *Playlist) RotationFilter() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.rotation == nil {
+		return ""
+	}
+	return p.rotation.String()
+}
+
+func (p *Playlist) retagOne(id string, fn func(*Song)) (Song, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.updateSongs(func(s Song) bool { return s.ID == id }, true, fn) == 0 {
+		return Song{}, fmt.Errorf("no song or radio segment with ID %s", id)
+	}
+	for _, list := range [][]Song{p.queue, p.randomNext} {
+		for _, s := range list {
+			if s.ID == id {
+				return s, nil
+			}
+		}
+	}
+	return Song{}, nil
+}
+
+// updateSongs applies fn to every matching master-list song, and radio
+// segment too if radio is set, including the shuffled decks' own copies;
+// caller holds p.mu. It returns how many list entries changed.
+func (p *Playlist) updateSongs(match func(Song) bool, radio bool, fn func(*Song)) int {
+	lists, decks := [][]Song{p.queue}, [][]Song{p.shuffledQueue}
+	if radio {
+		lists, decks = append(lists, p.randomNext), append(decks, p.shuffledRadio)
+	}
+	n := 0
+	for _, list := range lists {
+		for i := range list {
+			if match(list[i]) {
+				fn(&list[i])
+				n++
+			}
+		}
+	}
+	for _, deck := range decks {
+		for i := range deck {
+			if match(deck[i]) {
+				fn(&deck[i])
+			}
+		}
+	}
+	return n
+}
+
+func addTags(have, add []string) []string {
+	out := append([]string(nil), have...)
+	for _, t := range add {
+		if !containsTag(out, t) {
+			out = append(out, t)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+func dropTags(have, drop []string) []string {
+	var out []string
+	for _, t := range have {
+		if !containsTag(drop, t) {
+			out = append(out, t)
+		}
+	}
+	return out
+}
@@ -0,0 +1,98 @@
+package accessor
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestParseTags(t *testing.T) {
+	got := ParseTags(" Rock, lo fi,,LO-FI , rock,Synth  Wave")
+	if want := []string{"rock", "lo-fi", "synth-wave"}; !slices.Equal(got, want) {
+		t.Errorf("ParseTags = %q, want %q", got, want)
+	}
+}
+
+func TestTagExpr(t *testing.T) {
+	song := func(tags ...string) Song { return Song{ID: "s", Tags: tags} }
+	for _, tc := range []struct {
+		expr string
+		song Song
+		want bool
+	}{
+		{"rock", song("rock"), true},
+		{"ROCK", song("rock"), true},
+		{"rock", song("jazz"), false},
+		{"lo-fi", song("lo-fi"), true},
+		{"rock and not live", song("rock"), true},
+		{"rock and not live", song("rock", "live"), false},
+		{"rock & !live", song("rock", "live"), false},
+		{"rock -live", song("rock"), true},
+		{"rock -live", song("rock", "live"), false},
+		{"not not rock", song("rock"), true},
+		{"jazz or blues", song("blues"), true},
+		{"jazz | blues", song("rock"), false},
+		{"jazz,blues", song("jazz"), true},
+		// and binds tighter than or
+		{"jazz or blues chill", song("jazz"), true},
+		{"jazz or blues chill", song("blues"), false},
+		{"(jazz or blues) chill", song("jazz"), false},
+		{"(jazz or blues) chill", song("blues", "chill"), true},
+		{"!(jazz|blues)", song("rock"), true},
+		{"!(jazz|blues)", song("blues"), false},
+		{"((rock))", song("rock"), true},
+	} {
+		e, err := ParseTagExpr(tc.expr)
+		if err != nil {
+			t.Errorf("ParseTagExpr(%q): %v", tc.expr, err)
+			continue
+		}
+		if got := e.Matches(tc.song); got != tc.want {
+			t.Errorf("%q matching tags %q = %v, want %v", tc.expr, tc.song.Tags, got, tc.want)
+		}
+	}
+}
+
+func TestTagExprErrors(t *testing.T) {
+	for _, expr := range []string{
+		"",
+		"   ",
+		"rock and",
+		"or rock",
+		"rock or or jazz",
+		"not",
+		"(rock",
+		"rock)",
+		"()",
+		"rock & & jazz",
+	} {
+		if _, err := ParseTagExpr(expr); err == nil {
+			t.Errorf("ParseTagExpr(%q) succeeded, want an error", expr)
+		}
+	}
+}
+
+func TestBulkTagMasterListOnly(t *testing.T) {
+	p := newTestPlaylist(t, 0)
+	p.Add(Song{ID: "song", Name: "Alive (Live)"})
+	p.Add(Song{ID: "other", Name: "Studio Cut"})
+	p.AddRadio(Song{ID: "seg", Name: "Live from the Studio"})
+
+	n, err := p.BulkTag("live", []string{"live"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 1 {
+		t.Errorf("BulkTag tagged %d entries, want 1", n)
+	}
+	if got := p.TagCounts()["live"]; got != 1 {
+		t.Errorf("%d songs and segments carry the tag, want 1", got)
+	}
+
+	// single-song tagging still reaches segments
+	if _, err := p.Tag("seg", []string{"jingle"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := p.TagCounts()["jingle"]; got != 1 {
+		t.Errorf("%d segments carry the tag, want 1", got)
+	}
+}
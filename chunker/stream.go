@@ -0,0 +1,261 @@
+package chunker
+
+import (
+	"io"
+	"sync"
+	"time"
+)
+
+// BytesPerSecond is the data rate of 48 kHz DFPWM (8 samples per byte).
+const BytesPerSecond = 6000
+
+// Size is how many bytes of DFPWM play in one interval.
+func Size(interval time.Duration) int {
+	return int(BytesPerSecond * interval.Seconds())
+}
+
+// resumeAttempts is how many times in a row a Stream reopens its source
+// after a failed read without getting any data before it gives up;
+// resumeDelay spaces them out. Failing to open at all ends the stream
+// straight away, since openers do their own retrying.
+const resumeAttempts = 3
+
+var resumeDelay = 2 * time.Second // a var so tests can shorten it
+
+// Opener opens an audio source off bytes in.
+type Opener func(off int64) (io.ReadCloser, error)
+
+// Stream reads audio in the background into a fixed-size ring buffer and
+// hands it out a chunk at a time, so memory stays bounded however long the
+// track is. A source that fails part way is reopened where it left off.
+type Stream struct {
+	open  Opener
+	chunk int
+
+	mu     sync.Mutex
+	cond   *sync.Cond // signalled when the consumer frees space or the stream closes
+	ring   []byte
+	head   int   // read position in ring
+	size   int   // bytes buffered from head
+	err    error // why the reader stopped; io.EOF after a clean end
+	src    io.ReadCloser
+	closed bool
+
+	primed     chan struct{}
+	primedOnce sync.Once
+	done       chan struct{}
+}
+
+// NewStream starts reading from open(off), keeping up to ahead chunks of
+// chunkSize bytes buffered.
+func NewStream(open Opener, off int64, chunkSize, ahead int) *Stream {
+	if chunkSize <= 0 {
+		panic("chunk size ≤ 0")
+	}
+	if ahead < 1 {
+		ahead = 1
+	}
+	s := &Stream{
+		open:   open,
+		chunk:  chunkSize,
+		ring:   make([]byte, chunkSize*ahead),
+		primed: make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	s.cond = sync.NewCond(&s.mu)
+	go s.fill(off)
+	return s
+}
+
+// fill runs the reader: it reads into the free part of the ring, waiting
+// while the ring is full, and reopens the source after a failed read.
+func (s *Stream) fill(off int64) {
+	failures := 0
+	for {
+		if err := s.connect(off); err != nil {
+			// failures is zero only for the first open, which isn't a resume
+			if failures == 0 || failures >= resumeAttempts || !s.wait(resumeDelay) {
+				s.stop(err)
+				return
+			}
+			failures++
+			continue
+		}
+
+		s.mu.Lock()
+		for s.size == len(s.ring) && !s.closed {
+			s.cond.Wait()
+		}
+		if s.closed {
+			s.mu.Unlock()
+			return
+		}
+		// only the reader writes past head+size, so the region is safe to fill unlocked
+		tail := (s.head + s.size) % len(s.ring)
+		end := tail + len(s.ring) - s.size
+		if end > len(s.ring) {
+			end = len(s.ring)
+		}
+		src := s.src
+		s.mu.Unlock()
+
+		n, err := src.Read(s.ring[tail:end])
+		off += int64(n)
+		if n > 0 {
+			failures = 0
+		}
+
+		s.mu.Lock()
+		s.size += n
+		full := s.size == len(s.ring)
+		s.mu.Unlock()
+		if full {
+			s.prime()
+		}
+
+		switch {
+		case err == io.EOF:
+			s.stop(io.EOF)
+			return
+		case err != nil:
+			s.disconnect()
+			if failures++; failures > resumeAttempts || !s.wait(resumeDelay) {
+				s.stop(err)
+				return
+			}
+		}
+	}
+}
+
+// connect opens the source at off unless it is already open.
+func (s *Stream) connect(off int64) error {
+	s.mu.Lock()
+	open := s.src == nil && !s.closed
+	s.mu.Unlock()
+	if !open {
+		return nil
+	}
+	src, err := s.open(off)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		src.Close()
+		return nil
+	}
+	s.src = src
+	return nil
+}
+
+func (s *Stream) disconnect() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.src != nil {
+		s.src.Close()
+		s.src = nil
+	}
+}
+
+// wait sleeps for d, returning false if the stream is closed meanwhile.
+func (s *Stream) wait(d time.Duration) bool {
+	select {
+	case <-time.After(d):
+		return true
+	case <-s.done:
+		return false
+	}
+}
+
+func (s *Stream) stop(err error) {
+	s.disconnect()
+	s.mu.Lock()
+	if s.err == nil {
+		s.err = err
+	}
+	s.mu.Unlock()
+	s.prime()
+}
+
+func (s *Stream) prime() {
+	s.primedOnce.Do(func() { close(s.primed) })
+}
+
+// Primed is closed once the ring is full or the reader has stopped, i.e.
+// when playback can start without immediately running dry.
+func (s *Stream) Primed() <-chan struct{} {
+	return s.primed
+}
+
+// Next returns the next chunk (the last one may be short). It returns nil,
+// nil while the data hasn't arrived yet, and nil plus io.EOF or the read
+// error once the stream is used up.
+func (s *Stream) Next() ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := s.chunk
+	if s.size < n {
+		if s.err == nil {
+			return nil, nil
+		}
+		if s.size == 0 {
+			return nil, s.err
+		}
+		n = s.size
+	}
+	out := make([]byte, n)
+	first := copy(out, s.ring[s.head:min(s.head+n, len(s.ring))])
+	copy(out[first:], s.ring[:n-first])
+	s.discard(n)
+	return out, nil
+}
+
+// Skip drops the next n chunks if they are all buffered already, and
+// reports whether it did.
+func (s *Stream) Skip(n int) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if n*s.chunk > s.size {
+		return false
+	}
+	s.discard(n * s.chunk)
+	return true
+}
+
+// discard frees n buffered bytes; caller holds s.mu.
+func (s *Stream) discard(n int) {
+	s.head = (s.head + n) % len(s.ring)
+	s.size -= n
+	s.cond.Signal()
+}
+
+// Err reports why the reader stopped: nil while it is still going, io.EOF
+// after a clean end.
+func (s *Stream) Err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.err
+}
+
+// Drained reports whether the source ended cleanly and every chunk has been handed out.
+func (s *Stream) Drained() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.err == io.EOF && s.size == 0
+}
+
+// Close stops the reader and releases the source.
+func (s *Stream) Close() {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return
+	}
+	s.closed = true
+	close(s.done)
+	s.cond.Broadcast()
+	s.mu.Unlock()
+	s.disconnect()
+	s.prime()
+}
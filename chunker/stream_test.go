@@ -0,0 +1,227 @@
+package chunker
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// testSource serves data, failing a read when it reaches each offset in
+// failAt, and refusing every open after the first with openErr when set.
+type testSource struct {
+	data    []byte
+	failAt  []int64 // one mid-stream read failure at each of these offsets
+	openErr error   // returned by every open after the first when set
+
+	mu    sync.Mutex
+	opens []int64 // offset of each open, in order
+}
+
+var errRead = errors.New("connection reset")
+
+func (ts *testSource) open(off int64) (io.ReadCloser, error) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	ts.opens = append(ts.opens, off)
+	if ts.openErr != nil && len(ts.opens) > 1 {
+		return nil, ts.openErr
+	}
+	end := int64(len(ts.data))
+	for _, f := range ts.failAt {
+		if f > off {
+			end = f
+			break
+		}
+	}
+	return io.NopCloser(&failingReader{r: bytes.NewReader(ts.data[off:end]), fail: end < int64(len(ts.data))}), nil
+}
+
+func (ts *testSource) openOffsets() []int64 {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	return append([]int64(nil), ts.opens...)
+}
+
+// failingReader reads r, then fails instead of reporting io.EOF if fail is set.
+type failingReader struct {
+	r    io.Reader
+	fail bool
+}
+
+func (f *failingReader) Read(p []byte) (int, error) {
+	n, err := f.r.Read(p)
+	if err == io.EOF && f.fail {
+		err = errRead
+	}
+	return n, err
+}
+
+func testData(n int) []byte {
+	data := make([]byte, n)
+	for i := range data {
+		data[i] = byte(i)
+	}
+	return data
+}
+
+// fastResume shortens resumeDelay for the rest of the test.
+func fastResume(t *testing.T) {
+	old := resumeDelay
+	resumeDelay = time.Millisecond
+	t.Cleanup(func() { resumeDelay = old })
+}
+
+// drain reads s to the end, returning everything it handed out and the final error.
+func drain(t *testing.T, s *Stream) ([]byte, error) {
+	t.Helper()
+	var out []byte
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		chunk, err := s.Next()
+		if err != nil {
+			return out, err
+		}
+		if chunk == nil {
+			if time.Now().After(deadline) {
+				t.Fatal("stream stalled")
+			}
+			time.Sleep(time.Millisecond)
+			continue
+		}
+		out = append(out, chunk...)
+	}
+}
+
+func TestStreamChunks(t *testing.T) {
+	data := testData(10)
+	s := NewStream((&testSource{data: data}).open, 0, 4, 2)
+	defer s.Close()
+	<-s.Primed()
+	if s.Drained() {
+		t.Fatal("Drained before any chunk was handed out")
+	}
+
+	var sizes []int
+	for {
+		chunk, err := s.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if chunk == nil {
+			time.Sleep(time.Millisecond)
+			continue
+		}
+		sizes = append(sizes, len(chunk))
+	}
+	if want := []int{4, 4, 2}; !slices.Equal(sizes, want) {
+		t.Errorf("chunk sizes %v, want %v", sizes, want)
+	}
+	if !s.Drained() {
+		t.Error("not Drained after io.EOF")
+	}
+}
+
+func TestStreamSkip(t *testing.T) {
+	data := testData(40)
+	s := NewStream((&testSource{data: data}).open, 0, 4, 3)
+	defer s.Close()
+	<-s.Primed()
+
+	if s.Skip(4) {
+		t.Fatal("skipped 4 chunks with only 3 buffered")
+	}
+	if !s.Skip(2) {
+		t.Fatal("could not skip 2 buffered chunks")
+	}
+	rest, err := drain(t, s)
+	if err != io.EOF {
+		t.Fatalf("stream ended with %v, want io.EOF", err)
+	}
+	if !bytes.Equal(rest, data[8:]) {
+		t.Errorf("after skipping 2 chunks got %v, want %v", rest, data[8:])
+	}
+}
+
+func TestStreamOffset(t *testing.T) {
+	data := testData(20)
+	ts := &testSource{data: data}
+	s := NewStream(ts.open, 12, 4, 2)
+	defer s.Close()
+	got, err := drain(t, s)
+	if err != io.EOF || !bytes.Equal(got, data[12:]) {
+		t.Errorf("stream from 12 gave %v (%v), want %v", got, err, data[12:])
+	}
+}
+
+func TestStreamResumes(t *testing.T) {
+	fastResume(t)
+	data := testData(100)
+	ts := &testSource{data: data, failAt: []int64{30, 70}}
+	s := NewStream(ts.open, 0, 8, 2)
+	defer s.Close()
+
+	got, err := drain(t, s)
+	if err != io.EOF {
+		t.Fatalf("stream ended with %v, want io.EOF", err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Errorf("resumed stream gave %v, want %v", got, data)
+	}
+	if opens, want := ts.openOffsets(), []int64{0, 30, 70}; !slices.Equal(opens, want) {
+		t.Errorf("opened at %v, want %v", opens, want)
+	}
+}
+
+func TestStreamGivesUp(t *testing.T) {
+	fastResume(t)
+	errDown := errors.New("converter down")
+
+	t.Run("first open", func(t *testing.T) {
+		var opens atomic.Int32
+		s := NewStream(func(off int64) (io.ReadCloser, error) {
+			opens.Add(1)
+			return nil, errDown
+		}, 0, 4, 2)
+		defer s.Close()
+		<-s.Primed()
+		if err := s.Err(); err != errDown {
+			t.Errorf("Err() = %v, want %v", err, errDown)
+		}
+		if n := opens.Load(); n != 1 {
+			t.Errorf("a failed first open was retried: %d opens", n)
+		}
+	})
+
+	t.Run("resume", func(t *testing.T) {
+		data := testData(40)
+		ts := &testSource{data: data, failAt: []int64{10}, openErr: errDown}
+		s := NewStream(ts.open, 0, 4, 2)
+		defer s.Close()
+		got, err := drain(t, s)
+		if err != errDown {
+			t.Fatalf("stream ended with %v, want %v", err, errDown)
+		}
+		if !bytes.Equal(got, data[:10]) {
+			t.Errorf("stream gave %v before failing, want %v", got, data[:10])
+		}
+		// the first open, then resumeAttempts reopens at the failed offset
+		want := []int64{0}
+		for i := 0; i < resumeAttempts; i++ {
+			want = append(want, 10)
+		}
+		if opens := ts.openOffsets(); !slices.Equal(opens, want) {
+			t.Errorf("opened at %v, want %v", opens, want)
+		}
+		if s.Drained() {
+			t.Error("Drained after a failure")
+		}
+	})
+}
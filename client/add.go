@@ -0,0 +1,89 @@
+package client
+
+import (
+	"fmt"
+	"log/slog"
+
+	"github.com/Coop25/CC-Radio/accessor"
+	"github.com/bwmarrin/discordgo"
+)
+
+// handleAdd implements /addsong, /add-radio-segment and /addplaylist.
+// Resolving a playlist can take far longer than Discord's 3-second
+// deadline, so it defers the response and edits it as work progresses.
+func handleAdd(s *discordgo.Session, i *discordgo.InteractionCreate, resolver accessor.Resolver, gist *accessor.GistAccessor, pl *accessor.Playlist, log *slog.Logger) {
+	data := i.ApplicationCommandData()
+	url := data.Options[0].StringValue()
+
+	resolve, add, what := resolver.Resolve, pl.Add, "song"
+	switch data.Name {
+	case "add-radio-segment":
+		add, what = pl.AddRadio, "radio segment"
+	case "addplaylist":
+		resolve, what = resolver.ResolvePlaylist, "playlist"
+	}
+
+	if err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
+	}); err != nil {
+		log.Warn("defer response failed", "err", err)
+		return
+	}
+
+	edit := func(content string) {
+		if _, err := s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{Content: &content}); err != nil {
+			log.Warn("edit response failed", "err", err)
+		}
+	}
+
+	go func() {
+		edit(fmt.Sprintf("⏳ Resolving %s %q…", what, url))
+		found, err := resolve(url)
+		if err != nil {
+			log.Warn("command failed", "err", err)
+			edit(fmt.Sprintf("❌ Could not add %q: %v", url, err))
+			return
+		}
+		res := enqueue(found, add)
+		summary := formatLoadResult(res)
+		log.Info("loaded", "url", url, "added", res.Added, "duplicates", res.Duplicates, "skipped", res.Skipped)
+
+		if res.Added == 0 {
+			edit(fmt.Sprintf("ℹ️ Nothing new from %q — %s.", url, summary))
+			return
+		}
+
+		edit(fmt.Sprintf("⏳ %s; saving playlist…", summary))
+		if err := gist.SavePlaylist(pl); err != nil {
+			log.Warn("command failed", "err", err)
+			edit(fmt.Sprintf("❌ %s - but Save failed: %v", summary, err))
+			return
+		}
+		edit(fmt.Sprintf("✅ Queued %s %q — %s.", what, url, summary))
+	}()
+}
+
+// enqueue hands each resolved song to add (Playlist.Add or AddRadio) and
+// tallies the outcome.
+func enqueue(found accessor.Resolved, add func(accessor.Song) bool) accessor.LoadResult {
+	res := accessor.LoadResult{Skipped: found.Skipped}
+	for _, song := range found.Songs {
+		if add(song) {
+			res.Added++
+		} else {
+			res.Duplicates++
+		}
+	}
+	return res
+}
+
+func formatLoadResult(r accessor.LoadResult) string {
+	out := fmt.Sprintf("%d added", r.Added)
+	if r.Duplicates > 0 {
+		out += fmt.Sprintf(", %d already queued", r.Duplicates)
+	}
+	if r.Skipped > 0 {
+		out += fmt.Sprintf(", %d skipped", r.Skipped)
+	}
+	return out
+}
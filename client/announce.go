@@ -0,0 +1,44 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/Coop25/CC-Radio/dj"
+	"github.com/bwmarrin/discordgo"
+)
+
+var announceCommand = &discordgo.ApplicationCommand{
+	Name:        "announce",
+	Description: "Have the DJ voice read a message over the current track",
+	Options: []*discordgo.ApplicationCommandOption{
+		{Type: discordgo.ApplicationCommandOptionString, Name: "text", Description: "What to say", Required: true},
+	},
+}
+
+// handleAnnounce implements /announce. Synthesis can take a few seconds,
+// so the response is deferred.
+func handleAnnounce(s *discordgo.Session, i *discordgo.InteractionCreate, ann *dj.Announcer, log *slog.Logger) {
+	if ann == nil {
+		respond(s, i, "❌ No text-to-speech backend is configured (TTS_COMMAND or TTS_URL).", true)
+		return
+	}
+	text := i.ApplicationCommandData().Options[0].StringValue()
+	if err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
+	}); err != nil {
+		log.Warn("defer response failed", "err", err)
+		return
+	}
+	go func() {
+		content := fmt.Sprintf("📢 %q", text)
+		if err := ann.Say(context.Background(), text); err != nil {
+			log.Warn("command failed", "err", err)
+			content = "❌ " + err.Error()
+		}
+		if _, err := s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{Content: &content}); err != nil {
+			log.Warn("edit response failed", "err", err)
+		}
+	}()
+}
@@ -2,9 +2,13 @@
 package client
 
 import (
-	"log"
+	"encoding/json"
+	"log/slog"
 	"net/http"
 
+	"github.com/Coop25/CC-Radio/accessor"
+	"github.com/Coop25/CC-Radio/config"
+	"github.com/Coop25/CC-Radio/logging"
 	"github.com/Coop25/CC-Radio/manager"
 	"github.com/gorilla/websocket"
 )
@@ -15,16 +19,79 @@ var upgrader = websocket.Upgrader{
 	},
 }
 
-func RegisterWS(b *manager.Broadcaster) {
-	http.HandleFunc("/ws", wsHandler(b))
+// clientMsg is a JSON text frame sent by an in-game client.
+type clientMsg struct {
+	Type       string `json:"type"`
+	ComputerID int    `json:"computerId"`
+	Label      string `json:"label"`
+	World      string `json:"world"`
+	Value      int    `json:"value"` // rate: 1 like, -1 dislike, 0 clear
+	Song       string `json:"song"`  // voteskip: ID of the track the vote is for
 }
 
-func wsHandler(b *manager.Broadcaster) http.HandlerFunc {
+func RegisterWS(cfg *config.Config, b *manager.Broadcaster, ratings *accessor.Ratings, log *slog.Logger) {
+	log = logging.Component(log, "ws")
+	http.HandleFunc("/ws", wsHandler(b, ratings, log))
+	http.HandleFunc("/listeners", listenersHandler(b, cfg.ControlToken, log))
+	http.HandleFunc("/history", historyHandler(b, log))
+}
+
+// listenersHandler serves the listener registry as JSON. Remote addresses
+// are left out unless the request carries the CONTROL_TOKEN bearer token.
+func listenersHandler(b *manager.Broadcaster, token string, log *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		st := b.Listeners()
+		if token == "" || !hasToken(r, token) {
+			for i := range st.Listeners {
+				st.Listeners[i].RemoteAddr = ""
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(st); err != nil {
+			log.Error("listeners encode failed", "err", err)
+		}
+	}
+}
+
+// handleClientMessage dispatches one text frame from a client.
+func handleClientMessage(b *manager.Broadcaster, ratings *accessor.Ratings, log *slog.Logger, conn *websocket.Conn, data []byte) {
+	var msg clientMsg
+	if err := json.Unmarshal(data, &msg); err != nil {
+		log.Warn("ignoring malformed client message", "remote", conn.RemoteAddr().String(), "err", err)
+		return
+	}
+	switch msg.Type {
+	case "hello":
+		b.Identify(conn, msg.ComputerID, msg.Label, msg.World)
+	case "voteskip":
+		if !b.InGameVoting() {
+			return
+		}
+		if msg.Song == "" {
+			log.Debug("in-game vote rejected: no song given", "remote", conn.RemoteAddr().String())
+			return
+		}
+		if _, err := b.VoteSkip(b.ListenerVoterID(conn), msg.Song); err != nil {
+			log.Debug("in-game vote rejected", "err", err)
+		}
+	case "rate":
+		rater := b.ListenerRaterID(conn)
+		if rater == "" {
+			log.Debug("in-game rating rejected: client has not said hello", "remote", conn.RemoteAddr().String())
+			return
+		}
+		if song := b.NowPlaying().Song; song.ID != "" {
+			ratings.Rate(song.ID, rater, msg.Value)
+		}
+	}
+}
+
+func wsHandler(b *manager.Broadcaster, ratings *accessor.Ratings, log *slog.Logger) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		// 1) Perform the Upgrade
 		conn, err := upgrader.Upgrade(w, r, nil)
 		if err != nil {
-			log.Println("WebSocket upgrade failed:", err)
+			log.Warn("WebSocket upgrade failed", "err", err)
 			return
 		}
 
@@ -35,12 +102,16 @@ func wsHandler(b *manager.Broadcaster) http.HandlerFunc {
 			conn.Close()
 		}()
 
-		// 3) Block until client disconnects
+		// 3) Handle client messages until it disconnects
 		for {
-			if _, _, err := conn.ReadMessage(); err != nil {
+			mt, data, err := conn.ReadMessage()
+			if err != nil {
 				// client closed or network error
 				break
 			}
+			if mt == websocket.TextMessage {
+				handleClientMessage(b, ratings, log, conn, data)
+			}
 		}
 	}
 }
@@ -0,0 +1,67 @@
+package client
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Coop25/CC-Radio/accessor"
+	"github.com/bwmarrin/discordgo"
+)
+
+var converterCommand = &discordgo.ApplicationCommand{
+	Name:        "converter",
+	Description: "Show whether the audio converter service is reachable",
+}
+
+// handleConverter implements /converter.
+func handleConverter(s *discordgo.Session, i *discordgo.InteractionCreate, conv accessor.HealthReporter) {
+	respond(s, i, formatConverterHealth(conv.Health()), false)
+}
+
+func formatConverterHealth(h accessor.ConverterHealth) string {
+	var out string
+	switch h.State {
+	case accessor.ConverterDown:
+		out = "🔴 The converter is down; requests fail fast until an endpoint recovers."
+	case accessor.ConverterProbing:
+		out = "🟡 The converter was down; checking whether it is back."
+	default:
+		out = "🟢 The converter is up."
+	}
+	for _, be := range h.Backends {
+		if line := backendLine(be, len(h.Backends) > 1); line != "" {
+			out += "\n" + line
+		}
+	}
+	return out
+}
+
+// backendLine describes one endpoint; the URL is only worth showing when
+// there is more than one.
+func backendLine(be accessor.BackendHealth, many bool) string {
+	var out string
+	if many {
+		icon := "🟢"
+		switch be.State {
+		case accessor.ConverterDown:
+			icon = "🔴"
+		case accessor.ConverterProbing:
+			icon = "🟡"
+		}
+		out = fmt.Sprintf("%s `%s` (weight %d", icon, be.URL, be.Weight)
+		if be.Prefer != "" {
+			out += ", prefers " + be.Prefer
+		}
+		out += ")"
+	}
+	if be.State == accessor.ConverterDown {
+		out += fmt.Sprintf(" retry <t:%d:R>", be.RetryAt.Unix())
+	}
+	if be.Failures > 0 {
+		out += fmt.Sprintf(" %d failed requests in a row.", be.Failures)
+	}
+	if be.LastError != "" {
+		out += fmt.Sprintf(" Last error <t:%d:R>: `%s`", be.LastErrorAt.Unix(), truncate(be.LastError, 200))
+	}
+	return strings.TrimSpace(out)
+}
@@ -3,10 +3,16 @@ package client
 
 import (
 	"fmt"
-	"log"
+	"log/slog"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/Coop25/CC-Radio/accessor"
 	"github.com/Coop25/CC-Radio/config"
+	"github.com/Coop25/CC-Radio/dj"
+	"github.com/Coop25/CC-Radio/logging"
 	"github.com/Coop25/CC-Radio/manager"
 	"github.com/bwmarrin/discordgo"
 )
@@ -62,148 +68,237 @@ var commands = []*discordgo.ApplicationCommand{
 	},
 	{
 		Name:        "force-radio-segment",
-		Description: "force a radio segment to play next, may take 2 songs",
+		Description: "Play a radio segment right after the current track",
 	},
+	{
+		Name:        "listeners",
+		Description: "Show who is currently tuned in",
+	},
+	{
+		Name:        "voteskip",
+		Description: "Vote to skip the current song",
+	},
+	permissionsCommand,
+	libraryCommand,
+	interruptCommand,
+	announceCommand,
+	pauseCommand,
+	resumeCommand,
+	seekCommand,
+	forwardCommand,
+	rewindCommand,
+	replayCommand,
+	previousCommand,
+	scheduleCommand,
+	scheduleEditCommand,
+	tagCommand,
+	untagCommand,
+	bulkTagCommand,
+	tagsCommand,
+	rotationFilterCommand,
+	searchCommand,
+	converterCommand,
+	{
+		Name:        "removesong",
+		Description: "Remove a song from the master playlist",
+		Options: []*discordgo.ApplicationCommandOption{
+			{Type: discordgo.ApplicationCommandOptionString, Name: "song", Description: "Song to remove", Required: true, Autocomplete: true},
+		},
+	},
+	{
+		Name:        "remove-radio-segment",
+		Description: "Remove a radio segment",
+		Options: []*discordgo.ApplicationCommandOption{
+			{Type: discordgo.ApplicationCommandOptionString, Name: "segment", Description: "Segment to remove", Required: true, Autocomplete: true},
+		},
+	},
+	{
+		Name:        "removematching",
+		Description: "Remove every song whose name or artist matches a pattern",
+		Options: []*discordgo.ApplicationCommandOption{
+			{Type: discordgo.ApplicationCommandOptionString, Name: "pattern", Description: "Text or glob (e.g. *live*)", Required: true},
+			{Type: discordgo.ApplicationCommandOptionString, Name: "list", Description: "Which list (default songs)", Choices: libraryListChoices},
+			{Type: discordgo.ApplicationCommandOptionBoolean, Name: "confirm", Description: "Actually remove; without this only a preview is shown"},
+		},
+	},
+	{
+		Name:        "undo",
+		Description: "Restore the most recent removal",
+	},
+	{
+		Name:        "rate",
+		Description: "Like or dislike the current song",
+		Options: []*discordgo.ApplicationCommandOption{
+			{Type: discordgo.ApplicationCommandOptionInteger, Name: "value", Description: "Your rating", Required: true, Choices: rateChoices},
+		},
+	},
+	{
+		Name:        "history",
+		Description: "Show recently played tracks",
+		Options: []*discordgo.ApplicationCommandOption{
+			{Type: discordgo.ApplicationCommandOptionInteger, Name: "count", Description: "How many (1-25, default 10)"},
+		},
+	},
+	{
+		Name:        "stats",
+		Description: "Show top tracks, most skipped, airtime and library size",
+	},
+	{
+		Name:        "favorite",
+		Description: "Add or remove the current song from your favorites",
+	},
+	{
+		Name:        "favorites",
+		Description: "List favorite songs",
+		Options: []*discordgo.ApplicationCommandOption{
+			{Type: discordgo.ApplicationCommandOptionUser, Name: "user", Description: "Whose favorites (default yours)"},
+		},
+	},
+}
+
+// DiscordBot is the bot's session plus the connection state it reports to /readyz.
+type DiscordBot struct {
+	*discordgo.Session
+	connected atomic.Bool
+
+	appID   string
+	guildID string
+	cmdIDs  map[string]string // command name → registered ID
+
+	done      chan struct{} // closed by Close to stop background work
+	closeOnce sync.Once
+}
+
+// Close stops the bot's background work and the gateway session.
+func (d *DiscordBot) Close() error {
+	d.closeOnce.Do(func() { close(d.done) })
+	return d.Session.Close()
+}
+
+// Connected reports whether the gateway session is currently up.
+func (d *DiscordBot) Connected() bool {
+	return d.connected.Load()
 }
 
 // NewDiscordBot initializes, registers, and opens the Discord session.
 func NewDiscordBot(
 	cfg *config.Config,
 	b *manager.Broadcaster,
-	fetcher accessor.Fetcher,
+	resolver accessor.Resolver,
+	conv accessor.HealthReporter,
 	gist *accessor.GistAccessor,
 	pl *accessor.Playlist,
-) (*discordgo.Session, error) {
+	perms *accessor.Permissions,
+	ratings *accessor.Ratings,
+	sched *accessor.Schedule,
+	ann *dj.Announcer,
+	log *slog.Logger,
+) (*DiscordBot, error) {
+	log = logging.Component(log, "discord")
 
 	dg, err := discordgo.New("Bot " + cfg.DiscordToken)
 	if err != nil {
 		return nil, fmt.Errorf("discordgo.New: %w", err)
 	}
+	bot := &DiscordBot{
+		Session: dg,
+		guildID: cfg.DiscordGuildID,
+		cmdIDs:  make(map[string]string),
+		done:    make(chan struct{}),
+	}
+	clips := clipSource{b: b, pl: pl, dir: cfg.InterruptDir}
+
+	// track gateway state for readiness
+	dg.AddHandler(func(_ *discordgo.Session, _ *discordgo.Ready) { bot.connected.Store(true) })
+	dg.AddHandler(func(_ *discordgo.Session, _ *discordgo.Resumed) { bot.connected.Store(true) })
+	dg.AddHandler(func(_ *discordgo.Session, _ *discordgo.Disconnect) {
+		bot.connected.Store(false)
+		log.Warn("gateway disconnected")
+	})
 
 	if err := dg.Open(); err != nil {
 		return nil, fmt.Errorf("dg.Open: %w", err)
 	}
+	bot.connected.Store(true)
 
 	appID := dg.State.User.ID
 	guildID := cfg.DiscordGuildID
+	bot.appID = appID
 
 	// 1) DELETE all existing guild commands
 	existing, err := dg.ApplicationCommands(appID, guildID)
 	if err != nil {
-		log.Printf("⚠️  could not list existing commands: %v", err)
+		log.Warn("could not list existing commands", "err", err)
 	} else {
 		for _, cmd := range existing {
 			if err := dg.ApplicationCommandDelete(appID, guildID, cmd.ID); err != nil {
-				log.Printf("⚠️  failed to delete command %s: %v", cmd.Name, err)
+				log.Warn("failed to delete command", "command", cmd.Name, "err", err)
 			} else {
-				log.Printf("🗑️  deleted existing command %s", cmd.Name)
+				log.Debug("deleted existing command", "command", cmd.Name)
 			}
 		}
 	}
 
 	// 2) REGISTER your commands afresh
 	for _, cmd := range commands {
-		if _, err := dg.ApplicationCommandCreate(appID, guildID, cmd); err != nil {
-			log.Printf("❌ cannot create '%s' command: %v", cmd.Name, err)
+		cmd.DefaultMemberPermissions = defaultMemberPermissions(perms.Required(cmd.Name))
+		if created, err := dg.ApplicationCommandCreate(appID, guildID, cmd); err != nil {
+			log.Error("cannot create command", "command", cmd.Name, "err", err)
 		} else {
-			log.Printf("✅ registered command %s", cmd.Name)
+			bot.cmdIDs[cmd.Name] = created.ID
+			log.Debug("registered command", "command", cmd.Name)
 		}
 	}
 
+	if cfg.NowPlayingChannelID != "" {
+		panel := newNowPlayingPanel(dg, b, ratings, gist, cfg.NowPlayingChannelID, cfg.NowPlayingRefresh, log)
+		go panel.run(bot.done)
+	}
+
 	// Interaction handler
 	dg.AddHandler(func(s *discordgo.Session, i *discordgo.InteractionCreate) {
-		data := i.ApplicationCommandData()
-		switch data.Name {
-		case "addsong":
-			songID := data.Options[0].StringValue()
-			if err := fetcher.LoadSong(songID); err != nil {
-				s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
-					Type: discordgo.InteractionResponseChannelMessageWithSource,
-					Data: &discordgo.InteractionResponseData{
-						Content: fmt.Sprintf("❌ Could not add %q: %v", songID, err),
-						Flags:   discordgo.MessageFlagsEphemeral,
-					},
-				})
-				return
-			}
-
-			if err := gist.SavePlaylist(pl); err != nil {
-				s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
-					Type: discordgo.InteractionResponseChannelMessageWithSource,
-					Data: &discordgo.InteractionResponseData{
-						Content: fmt.Sprintf("❌ Track added - but Save failed: %v", err),
-						Flags:   discordgo.MessageFlagsEphemeral,
-					},
-				})
-				return
-			}
-
-			s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
-				Type: discordgo.InteractionResponseChannelMessageWithSource,
-				Data: &discordgo.InteractionResponseData{
-					Content: fmt.Sprintf("✅ Queued song %q!", songID),
-				},
-			})
-		case "add-radio-segment":
-			songID := data.Options[0].StringValue()
-			if err := fetcher.LoadRadioSegment(songID); err != nil {
-				s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
-					Type: discordgo.InteractionResponseChannelMessageWithSource,
-					Data: &discordgo.InteractionResponseData{
-						Content: fmt.Sprintf("❌ Could not add %q: %v", songID, err),
-						Flags:   discordgo.MessageFlagsEphemeral,
-					},
-				})
-				return
-			}
-
-			if err := gist.SavePlaylist(pl); err != nil {
-				s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
-					Type: discordgo.InteractionResponseChannelMessageWithSource,
-					Data: &discordgo.InteractionResponseData{
-						Content: fmt.Sprintf("❌ RadioSegment added - but Save failed: %v", err),
-						Flags:   discordgo.MessageFlagsEphemeral,
-					},
-				})
-				return
-			}
-
-			s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
-				Type: discordgo.InteractionResponseChannelMessageWithSource,
-				Data: &discordgo.InteractionResponseData{
-					Content: fmt.Sprintf("✅ Queued Segment %q!", songID),
-				},
-			})
-
-		case "addplaylist":
-			songID := data.Options[0].StringValue()
-			if err := fetcher.LoadPlaylist(songID); err != nil {
-				s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
-					Type: discordgo.InteractionResponseChannelMessageWithSource,
-					Data: &discordgo.InteractionResponseData{
-						Content: fmt.Sprintf("❌ Could not add %q: %v", songID, err),
-						Flags:   discordgo.MessageFlagsEphemeral,
-					},
-				})
-				return
+		if i.Type == discordgo.InteractionMessageComponent {
+			cdata := i.MessageComponentData()
+			log.Debug("component clicked", "request_id", i.ID, "custom_id", cdata.CustomID, "user", interactionUser(i))
+			switch {
+			case strings.HasPrefix(cdata.CustomID, voteSkipButtonPrefix):
+				if checkPermission(s, i, perms, "voteskip") {
+					handleVoteSkip(s, i, b)
+				}
+			case strings.HasPrefix(cdata.CustomID, libraryButtonPrefix):
+				handleLibrary(s, i, pl)
+			case cdata.CustomID == npLike || cdata.CustomID == npDislike:
+				if checkPermission(s, i, perms, "rate") {
+					handleRatingButton(s, i, b, ratings)
+				}
+			case cdata.CustomID == npFavorite:
+				if checkPermission(s, i, perms, "favorite") {
+					handleRatingButton(s, i, b, ratings)
+				}
+			case strings.HasPrefix(cdata.CustomID, npButtonPrefix):
+				handleNowPlayingButton(s, i, b, gist, pl, perms)
 			}
+			return
+		}
+		if i.Type == discordgo.InteractionApplicationCommandAutocomplete {
+			handleSongAutocomplete(s, i, pl, clips)
+			return
+		}
+		if i.Type != discordgo.InteractionApplicationCommand {
+			return
+		}
 
-			if err := gist.SavePlaylist(pl); err != nil {
-				s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
-					Type: discordgo.InteractionResponseChannelMessageWithSource,
-					Data: &discordgo.InteractionResponseData{
-						Content: fmt.Sprintf("❌ Track added - but Save failed: %v", err),
-						Flags:   discordgo.MessageFlagsEphemeral,
-					},
-				})
-				return
-			}
-			s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
-				Type: discordgo.InteractionResponseChannelMessageWithSource,
-				Data: &discordgo.InteractionResponseData{
-					Content: fmt.Sprintf("✅ Queued song %q!", songID),
-				},
-			})
+		data := i.ApplicationCommandData()
+		discordCommands.Inc(data.Name)
+		rlog := log.With("request_id", i.ID, "command", data.Name, "user", interactionUser(i))
+		rlog.Info("command received")
+		if !checkPermission(s, i, perms, data.Name) {
+			rlog.Info("command denied")
+			return
+		}
+		switch data.Name {
+		case "addsong", "add-radio-segment", "addplaylist":
+			handleAdd(s, i, resolver, gist, pl, rlog)
 
 		case "skip":
 			b.Skip()
@@ -219,11 +314,12 @@ func NewDiscordBot(
 			s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
 				Type: discordgo.InteractionResponseChannelMessageWithSource,
 				Data: &discordgo.InteractionResponseData{
-					Content: "⏭️ Forced a radio segment to be played withing the next couple songs.",
+					Content: "⏭️ A radio segment will play after the current track.",
 				},
 			})
 		case "saveplaylist":
 			if err := gist.SavePlaylist(pl); err != nil {
+				rlog.Warn("command failed", "err", err)
 				s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
 					Type: discordgo.InteractionResponseChannelMessageWithSource,
 					Data: &discordgo.InteractionResponseData{
@@ -242,6 +338,7 @@ func NewDiscordBot(
 		case "deletecurrent":
 			err := b.DeleteCurrent()
 			if err != nil {
+				rlog.Warn("command failed", "err", err)
 				s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
 					Type: discordgo.InteractionResponseChannelMessageWithSource,
 					Data: &discordgo.InteractionResponseData{
@@ -251,6 +348,7 @@ func NewDiscordBot(
 				})
 			} else {
 				if err := gist.SavePlaylist(pl); err != nil {
+					rlog.Warn("command failed", "err", err)
 					s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
 						Type: discordgo.InteractionResponseChannelMessageWithSource,
 						Data: &discordgo.InteractionResponseData{
@@ -267,9 +365,96 @@ func NewDiscordBot(
 					},
 				})
 			}
+		case "permissions":
+			bot.handlePermissions(s, i, gist, perms, rlog)
+		case "removesong", "remove-radio-segment", "removematching", "undo":
+			handleRemove(s, i, gist, pl, rlog)
+		case "rate", "favorite", "favorites":
+			handleRatingCommand(s, i, b, pl, ratings)
+		case "tag", "untag", "bulktag", "tags", "rotation-filter":
+			handleTagCommand(s, i, gist, pl, rlog)
+		case "replay", "previous":
+			handleReplay(s, i, b)
+		case "seek", "forward", "rewind":
+			handleSeek(s, i, b)
+		case "pause", "resume":
+			handlePause(s, i, b)
+		case "announce":
+			handleAnnounce(s, i, ann, rlog)
+		case "interrupt":
+			handleInterrupt(s, i, clips, rlog)
+		case "schedule":
+			handleSchedule(s, i, sched, pl)
+		case "schedule-edit":
+			handleScheduleEdit(s, i, gist, sched, pl, rlog)
+		case "history":
+			handleHistory(s, i, b)
+		case "stats":
+			handleStats(s, i, b, pl)
+		case "library":
+			handleLibrary(s, i, pl)
+		case "search":
+			handleSearch(s, i, pl)
+		case "voteskip":
+			handleVoteSkip(s, i, b)
+		case "converter":
+			handleConverter(s, i, conv)
+		case "listeners":
+			s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+				Type: discordgo.InteractionResponseChannelMessageWithSource,
+				Data: &discordgo.InteractionResponseData{
+					Embeds: []*discordgo.MessageEmbed{listenersEmbed(b.Listeners())},
+				},
+			})
 		}
 
 	})
 
-	return dg, nil
+	return bot, nil
+}
+
+// listenersEmbed renders the listener registry for /listeners.
+func listenersEmbed(st manager.ListenerStats) *discordgo.MessageEmbed {
+	var sb strings.Builder
+	if len(st.Listeners) == 0 {
+		sb.WriteString("Nobody is tuned in right now.")
+	}
+	for _, l := range st.Listeners {
+		name := l.RemoteAddr
+		if l.Identified {
+			name = fmt.Sprintf("#%d", l.ComputerID)
+			if l.Label != "" {
+				name += " " + l.Label
+			}
+			if l.World != "" {
+				name += " @ " + l.World
+			}
+		}
+		fmt.Fprintf(&sb, "• **%s** — %s, %.1f KiB sent",
+			name, time.Since(l.ConnectedAt).Round(time.Second), float64(l.BytesSent)/1024)
+		if l.DroppedFrames > 0 {
+			fmt.Fprintf(&sb, ", %d dropped", l.DroppedFrames)
+		}
+		sb.WriteString("\n")
+	}
+
+	return &discordgo.MessageEmbed{
+		Title:       fmt.Sprintf("📻 %d listening", st.Current),
+		Description: sb.String(),
+		Fields: []*discordgo.MessageEmbedField{
+			{Name: "Peak", Value: fmt.Sprintf("%d", st.Peak), Inline: true},
+			{Name: "Average (24h)", Value: fmt.Sprintf("%.1f", st.Average), Inline: true},
+		},
+	}
+}
+
+// interactionUser returns the ID of whoever triggered i, in a guild or a DM.
+func interactionUser(i *discordgo.InteractionCreate) string {
+	if i.Member != nil && i.Member.User != nil {
+		return i.Member.User.ID
+	}
+	if i.User != nil {
+		return i.User.ID
+	}
+	return ""
 }
@@ -0,0 +1,78 @@
+package client
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/Coop25/CC-Radio/accessor"
+	"github.com/Coop25/CC-Radio/manager"
+)
+
+var startedAt = time.Now()
+
+type checkResult struct {
+	OK     bool        `json:"ok"`
+	Detail interface{} `json:"detail,omitempty"`
+}
+
+type healthResponse struct {
+	Status string                 `json:"status"`
+	Uptime string                 `json:"uptime"`
+	Checks map[string]checkResult `json:"checks,omitempty"`
+}
+
+// RegisterHealth serves /healthz (process alive) and /readyz (actually streaming).
+func RegisterHealth(b *manager.Broadcaster, gist *accessor.GistAccessor, conv accessor.HealthReporter, bot *DiscordBot) {
+	http.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		writeHealth(w, http.StatusOK, healthResponse{
+			Status: "ok",
+			Uptime: time.Since(startedAt).Round(time.Second).String(),
+		})
+	})
+	http.HandleFunc("/readyz", readyHandler(b, gist, conv, bot))
+}
+
+// nextFetchGrace is how long the next track may still be fetching before /readyz
+// reports it; every rotation and replan starts a fresh background fetch.
+const nextFetchGrace = 30 * time.Second
+
+func nextReady(bs manager.Status) bool {
+	return bs.NextPrefetched || (!bs.NextFetchingSince.IsZero() && time.Since(bs.NextFetchingSince) < nextFetchGrace)
+}
+
+func readyHandler(b *manager.Broadcaster, gist *accessor.GistAccessor, conv accessor.HealthReporter, bot *DiscordBot) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		bs := b.Status()
+		ss := gist.Status()
+		cs := conv.Health()
+		checks := map[string]checkResult{
+			"playlist_loaded":   {OK: ss.Loaded, Detail: ss},
+			"broadcaster":       {OK: bs.Started && bs.Ticking, Detail: bs},
+			"next_prefetched":   {OK: nextReady(bs), Detail: bs.NextSong},
+			"discord_connected": {OK: bot.Connected()},
+			"converter":         {OK: cs.State != accessor.ConverterDown, Detail: cs},
+		}
+
+		resp := healthResponse{
+			Status: "ok",
+			Uptime: time.Since(startedAt).Round(time.Second).String(),
+			Checks: checks,
+		}
+		code := http.StatusOK
+		for _, c := range checks {
+			if !c.OK {
+				resp.Status = "unavailable"
+				code = http.StatusServiceUnavailable
+				break
+			}
+		}
+		writeHealth(w, code, resp)
+	}
+}
+
+func writeHealth(w http.ResponseWriter, code int, resp healthResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(resp)
+}
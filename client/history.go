@@ -0,0 +1,100 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Coop25/CC-Radio/accessor"
+	"github.com/Coop25/CC-Radio/manager"
+	"github.com/bwmarrin/discordgo"
+)
+
+const (
+	defaultHistoryLimit = 10
+	statsTopN           = 5
+)
+
+// historyHandler serves the play history as JSON; ?limit=N caps it (default all).
+func historyHandler(b *manager.Broadcaster, log *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(b.History(limit)); err != nil {
+			log.Error("history encode failed", "err", err)
+		}
+	}
+}
+
+// handleHistory answers /history with the most recent plays.
+func handleHistory(s *discordgo.Session, i *discordgo.InteractionCreate, b *manager.Broadcaster) {
+	limit := defaultHistoryLimit
+	if opts := i.ApplicationCommandData().Options; len(opts) > 0 {
+		limit = int(opts[0].IntValue())
+	}
+	if limit < 1 || limit > 25 {
+		limit = defaultHistoryLimit
+	}
+
+	var sb strings.Builder
+	for _, r := range b.History(limit) {
+		status := ""
+		switch {
+		case r.EndedAt.IsZero():
+			status = " ▶️ _now playing_"
+		case r.Skipped:
+			status = " ⏭️"
+		}
+		fmt.Fprintf(&sb, "<t:%d:t> **%s** — %s (%d listening)%s\n",
+			r.StartedAt.Unix(), r.Name, r.Artist, r.Listeners, status)
+	}
+	if sb.Len() == 0 {
+		sb.WriteString("Nothing has played yet.")
+	}
+
+	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Embeds: []*discordgo.MessageEmbed{{Title: "🕘 Recently played", Description: sb.String()}},
+		},
+	})
+}
+
+// handleStats answers /stats with play counts, skips, airtime and library size.
+func handleStats(s *discordgo.Session, i *discordgo.InteractionCreate, b *manager.Broadcaster, pl *accessor.Playlist) {
+	st := b.PlayStats(statsTopN)
+
+	list := func(tcs []manager.TrackCount) string {
+		if len(tcs) == 0 {
+			return "—"
+		}
+		var sb strings.Builder
+		for n, tc := range tcs {
+			fmt.Fprintf(&sb, "%d. **%s** — %s ×%d\n", n+1, tc.Name, tc.Artist, tc.Count)
+		}
+		return sb.String()
+	}
+
+	since := "—"
+	if !st.Since.IsZero() {
+		since = fmt.Sprintf("<t:%d:R>", st.Since.Unix())
+	}
+	embed := &discordgo.MessageEmbed{
+		Title: "📊 Station stats",
+		Fields: []*discordgo.MessageEmbedField{
+			{Name: "Library", Value: fmt.Sprintf("%d songs, %d radio segments", len(pl.Songs()), len(pl.RadioSegments())), Inline: true},
+			{Name: "Plays", Value: fmt.Sprintf("%d (%d skipped)", st.Plays, st.Skips), Inline: true},
+			{Name: "Airtime", Value: fmt.Sprintf("%s since %s", st.Airtime.Round(time.Minute), since), Inline: true},
+			{Name: "Top tracks", Value: list(st.TopPlayed)},
+			{Name: "Most skipped", Value: list(st.MostSkipped)},
+		},
+	}
+	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{Embeds: []*discordgo.MessageEmbed{embed}},
+	})
+}
@@ -0,0 +1,179 @@
+package client
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/Coop25/CC-Radio/accessor"
+	"github.com/Coop25/CC-Radio/config"
+	"github.com/Coop25/CC-Radio/logging"
+	"github.com/Coop25/CC-Radio/manager"
+	"github.com/bwmarrin/discordgo"
+)
+
+var interruptCommand = &discordgo.ApplicationCommand{
+	Name:        "interrupt",
+	Description: "Play a clip over the current track, then pick the track up where it left off",
+	Options: []*discordgo.ApplicationCommandOption{
+		{Type: discordgo.ApplicationCommandOptionString, Name: "segment", Description: "Radio segment to play", Autocomplete: true},
+		{Type: discordgo.ApplicationCommandOptionString, Name: "song", Description: "Song to play", Autocomplete: true},
+		{Type: discordgo.ApplicationCommandOptionString, Name: "file", Description: "DFPWM clip from the station's clip folder", Autocomplete: true},
+	},
+}
+
+// clipSource resolves interrupt requests from Discord and HTTP to audio.
+type clipSource struct {
+	b   *manager.Broadcaster
+	pl  *accessor.Playlist
+	dir string // INTERRUPT_DIR
+}
+
+// play queues the clip kind ("segment", "song" or "file") named by value and
+// returns its title. A song is fetched first, until ctx ends at the latest.
+func (c clipSource) play(ctx context.Context, kind, value string) (string, error) {
+	switch kind {
+	case "segment", "song":
+		song, ok := c.pl.Lookup(value)
+		if !ok {
+			return "", fmt.Errorf("no song or radio segment with ID %s", value)
+		}
+		return song.Name, c.b.InterruptSong(ctx, song)
+	case "file":
+		data, err := c.readClip(value)
+		if err != nil {
+			return "", err
+		}
+		return value, c.b.InterruptAudio(value, data)
+	}
+	return "", fmt.Errorf("pick a segment, song or file to play")
+}
+
+// readClip loads a file from the clip folder; names may not leave it.
+func (c clipSource) readClip(name string) ([]byte, error) {
+	if c.dir == "" {
+		return nil, fmt.Errorf("no clip folder configured (INTERRUPT_DIR)")
+	}
+	if name != filepath.Base(name) || strings.HasPrefix(name, ".") {
+		return nil, fmt.Errorf("invalid clip name %q", name)
+	}
+	data, err := os.ReadFile(filepath.Join(c.dir, name))
+	if err != nil {
+		return nil, fmt.Errorf("read clip %q: %w", name, err)
+	}
+	return data, nil
+}
+
+// clips lists the clip folder for autocomplete.
+func (c clipSource) clips() []string {
+	if c.dir == "" {
+		return nil
+	}
+	entries, err := os.ReadDir(c.dir)
+	if err != nil {
+		return nil
+	}
+	var names []string
+	for _, e := range entries {
+		if e.Type().IsRegular() && !strings.HasPrefix(e.Name(), ".") {
+			names = append(names, e.Name())
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// handleInterrupt implements /interrupt. Fetching a song can outlast
+// Discord's deadline, so the response is deferred like /addsong's.
+func handleInterrupt(s *discordgo.Session, i *discordgo.InteractionCreate, src clipSource, log *slog.Logger) {
+	opts := i.ApplicationCommandData().Options
+	if len(opts) != 1 {
+		respond(s, i, "❌ Pick exactly one of segment, song or file.", true)
+		return
+	}
+	kind, value := opts[0].Name, opts[0].StringValue()
+
+	if err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
+	}); err != nil {
+		log.Warn("defer response failed", "err", err)
+		return
+	}
+	go func() {
+		content := ""
+		title, err := src.play(context.Background(), kind, value)
+		if err != nil {
+			log.Warn("command failed", "err", err)
+			content = "❌ " + err.Error()
+		} else {
+			content = fmt.Sprintf("🔴 Interrupting with **%s**; the current track resumes afterwards.", title)
+		}
+		if _, err := s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{Content: &content}); err != nil {
+			log.Warn("edit response failed", "err", err)
+		}
+	}()
+}
+
+// RegisterControl serves the HTTP control endpoints, guarded by CONTROL_TOKEN.
+// Without a token they are not registered at all.
+func RegisterControl(cfg *config.Config, b *manager.Broadcaster, pl *accessor.Playlist, log *slog.Logger) {
+	log = logging.Component(log, "control")
+	if cfg.ControlToken == "" {
+		log.Info("HTTP control endpoints disabled; set CONTROL_TOKEN to enable")
+		return
+	}
+	src := clipSource{b: b, pl: pl, dir: cfg.InterruptDir}
+	http.HandleFunc("/control/interrupt", requireToken(cfg.ControlToken, interruptHandler(src, log)))
+	http.HandleFunc("/control/pause", requireToken(cfg.ControlToken, pauseHandler(b, log)))
+	http.HandleFunc("/control/resume", requireToken(cfg.ControlToken, pauseHandler(b, log)))
+}
+
+// requireToken rejects requests without "Authorization: Bearer <token>".
+func requireToken(token string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !hasToken(r, token) {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next(w, r)
+	}
+}
+
+// hasToken reports whether r carries "Authorization: Bearer <token>".
+func hasToken(r *http.Request, token string) bool {
+	got := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return subtle.ConstantTimeCompare([]byte(got), []byte(token)) == 1
+}
+
+// interruptHandler queues a clip: POST /control/interrupt?segment=ID (or song=ID, file=name).
+func interruptHandler(src clipSource, log *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		var kind, value string
+		for _, k := range []string{"segment", "song", "file"} {
+			if v := r.FormValue(k); v != "" {
+				kind, value = k, v
+				break
+			}
+		}
+		title, err := src.play(r.Context(), kind, value)
+		if err != nil {
+			log.Warn("interrupt failed", "kind", kind, "value", value, "err", err)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		log.Info("interrupt queued", "kind", kind, "title", title)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]string{"queued": title})
+	}
+}
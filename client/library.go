@@ -0,0 +1,239 @@
+package client
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Coop25/CC-Radio/accessor"
+	"github.com/bwmarrin/discordgo"
+)
+
+const (
+	libraryPageSize = 10
+	searchLimit     = 10
+	// custom IDs look like "library:<songs|radio>:<page>"
+	libraryButtonPrefix = "library:"
+)
+
+var libraryListChoices = []*discordgo.ApplicationCommandOptionChoice{
+	{Name: "songs", Value: "songs"},
+	{Name: "radio segments", Value: "radio"},
+}
+
+var libraryCommand = &discordgo.ApplicationCommand{
+	Name:        "library",
+	Description: "Browse the master playlist or the radio segments",
+	Options: []*discordgo.ApplicationCommandOption{
+		{Type: discordgo.ApplicationCommandOptionString, Name: "list", Description: "Which list to browse (default songs)", Choices: libraryListChoices},
+		{Type: discordgo.ApplicationCommandOptionInteger, Name: "page", Description: "Page to open"},
+	},
+}
+
+var searchCommand = &discordgo.ApplicationCommand{
+	Name:        "search",
+	Description: "Search songs and radio segments by name or artist",
+	Options: []*discordgo.ApplicationCommandOption{
+		{Type: discordgo.ApplicationCommandOptionString, Name: "text", Description: "What to look for", Required: true},
+	},
+}
+
+// handleLibrary answers /library and its page buttons.
+func handleLibrary(s *discordgo.Session, i *discordgo.InteractionCreate, pl *accessor.Playlist) {
+	list, page := "songs", 1
+	respType := discordgo.InteractionResponseChannelMessageWithSource
+
+	if i.Type == discordgo.InteractionMessageComponent {
+		parts := strings.Split(strings.TrimPrefix(i.MessageComponentData().CustomID, libraryButtonPrefix), ":")
+		if len(parts) == 2 {
+			list = parts[0]
+			page, _ = strconv.Atoi(parts[1])
+		}
+		respType = discordgo.InteractionResponseUpdateMessage
+	} else {
+		for _, o := range i.ApplicationCommandData().Options {
+			switch o.Name {
+			case "list":
+				list = o.StringValue()
+			case "page":
+				page = int(o.IntValue())
+			}
+		}
+	}
+
+	songs, title := pl.Songs(), "Songs"
+	if list == "radio" {
+		songs, title = pl.RadioSegments(), "Radio segments"
+	}
+	embed, components := libraryPage(title, list, songs, page)
+	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: respType,
+		Data: &discordgo.InteractionResponseData{
+			Embeds:     []*discordgo.MessageEmbed{embed},
+			Components: components,
+		},
+	})
+}
+
+// libraryPage renders one page of songs with prev/next buttons.
+func libraryPage(title, list string, songs []accessor.Song, page int) (*discordgo.MessageEmbed, []discordgo.MessageComponent) {
+	pages := (len(songs) + libraryPageSize - 1) / libraryPageSize
+	if pages == 0 {
+		pages = 1
+	}
+	if page < 1 {
+		page = 1
+	}
+	if page > pages {
+		page = pages
+	}
+
+	var sb strings.Builder
+	start := (page - 1) * libraryPageSize
+	for n := start; n < len(songs) && n < start+libraryPageSize; n++ {
+		fmt.Fprintf(&sb, "%d. %s\n", n+1, songLine(songs[n]))
+	}
+	if len(songs) == 0 {
+		sb.WriteString("_empty_")
+	}
+
+	embed := &discordgo.MessageEmbed{
+		Title:       fmt.Sprintf("📚 %s (%d)", title, len(songs)),
+		Description: sb.String(),
+		Footer:      &discordgo.MessageEmbedFooter{Text: fmt.Sprintf("Page %d/%d", page, pages)},
+	}
+	components := []discordgo.MessageComponent{
+		discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+			discordgo.Button{
+				Label:    "◀ Prev",
+				Style:    discordgo.SecondaryButton,
+				CustomID: fmt.Sprintf("%s%s:%d", libraryButtonPrefix, list, page-1),
+				Disabled: page <= 1,
+			},
+			discordgo.Button{
+				Label:    "Next ▶",
+				Style:    discordgo.SecondaryButton,
+				CustomID: fmt.Sprintf("%s%s:%d", libraryButtonPrefix, list, page+1),
+				Disabled: page >= pages,
+			},
+		}},
+	}
+	return embed, components
+}
+
+// handleSearch answers /search with the best fuzzy matches.
+func handleSearch(s *discordgo.Session, i *discordgo.InteractionCreate, pl *accessor.Playlist) {
+	query := i.ApplicationCommandData().Options[0].StringValue()
+	matches := pl.Search(query, searchLimit)
+
+	var sb strings.Builder
+	for _, m := range matches {
+		tag := ""
+		if m.Radio {
+			tag = " 📻"
+		}
+		fmt.Fprintf(&sb, "• %s%s\n", songLine(m.Song), tag)
+	}
+	if len(matches) == 0 {
+		sb.WriteString("No matches.")
+	}
+
+	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Embeds: []*discordgo.MessageEmbed{{
+				Title:       fmt.Sprintf("🔎 %q", query),
+				Description: sb.String(),
+			}},
+			Flags: discordgo.MessageFlagsEphemeral,
+		},
+	})
+}
+
+// handleSongAutocomplete suggests song IDs for any option named "song"
+// (master list) or "segment" (radio segments), and clip names for "file".
+func handleSongAutocomplete(s *discordgo.Session, i *discordgo.InteractionCreate, pl *accessor.Playlist, clips clipSource) {
+	var focused *discordgo.ApplicationCommandInteractionDataOption
+	for _, o := range flattenOptions(i.ApplicationCommandData().Options) {
+		if o.Focused {
+			focused = o
+		}
+	}
+	if focused == nil {
+		return
+	}
+
+	choices := make([]*discordgo.ApplicationCommandOptionChoice, 0, 25)
+	if focused.Name == "file" {
+		query := strings.ToLower(focused.StringValue())
+		for _, name := range clips.clips() {
+			if !strings.Contains(strings.ToLower(name), query) {
+				continue
+			}
+			choices = append(choices, &discordgo.ApplicationCommandOptionChoice{Name: truncate(name, 100), Value: name})
+			if len(choices) == 25 {
+				break
+			}
+		}
+	} else {
+		// /tag and /untag take songs and segments alike
+		command := i.ApplicationCommandData().Name
+		either := command == "tag" || command == "untag"
+		for _, m := range pl.Search(focused.StringValue(), 0) {
+			if !either && (focused.Name == "segment") != m.Radio {
+				continue
+			}
+			name := m.Song.Name + " — " + m.Song.Artist
+			if either && m.Radio {
+				name = "📻 " + name
+			}
+			choices = append(choices, &discordgo.ApplicationCommandOptionChoice{
+				Name:  truncate(name, 100),
+				Value: m.Song.ID,
+			})
+			if len(choices) == 25 {
+				break
+			}
+		}
+	}
+
+	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionApplicationCommandAutocompleteResult,
+		Data: &discordgo.InteractionResponseData{Choices: choices},
+	})
+}
+
+// flattenOptions walks into subcommands so handlers see leaf options.
+func flattenOptions(opts []*discordgo.ApplicationCommandInteractionDataOption) []*discordgo.ApplicationCommandInteractionDataOption {
+	var out []*discordgo.ApplicationCommandInteractionDataOption
+	for _, o := range opts {
+		if o.Type == discordgo.ApplicationCommandOptionSubCommand || o.Type == discordgo.ApplicationCommandOptionSubCommandGroup {
+			out = append(out, flattenOptions(o.Options)...)
+			continue
+		}
+		out = append(out, o)
+	}
+	return out
+}
+
+func songLine(s accessor.Song) string {
+	line := fmt.Sprintf("**%s** — %s (%s) `%s`", s.Name, s.Artist, formatDuration(s.Duration), s.ID)
+	if len(s.Tags) > 0 {
+		line += " " + formatTags(s.Tags)
+	}
+	return line
+}
+
+func formatDuration(d time.Duration) string {
+	d = d.Round(time.Second)
+	return fmt.Sprintf("%d:%02d", int(d.Minutes()), int(d.Seconds())%60)
+}
+
+func truncate(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n-1]) + "…"
+}
@@ -0,0 +1,15 @@
+package client
+
+import (
+	"net/http"
+
+	"github.com/Coop25/CC-Radio/metrics"
+)
+
+var discordCommands = metrics.NewCounterVec("ccradio_discord_commands_total",
+	"Discord slash commands received, by command name.", "command")
+
+// RegisterMetrics exposes the default registry on /metrics.
+func RegisterMetrics() {
+	http.Handle("/metrics", metrics.Default.Handler())
+}
@@ -0,0 +1,240 @@
+package client
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Coop25/CC-Radio/accessor"
+	"github.com/Coop25/CC-Radio/manager"
+	"github.com/bwmarrin/discordgo"
+)
+
+// custom IDs of the now-playing panel buttons
+const (
+	npButtonPrefix = "np:"
+	npSkip         = npButtonPrefix + "skip"
+	npVote         = npButtonPrefix + "vote:" // + song ID, so a vote lands on the track it was cast for
+	npRemove       = npButtonPrefix + "remove"
+)
+
+const progressBarWidth = 16
+
+// nowPlayingPanel keeps a single embed in a channel up to date with what is
+// on air, editing it in place rather than posting a message per track.
+type nowPlayingPanel struct {
+	s         *discordgo.Session
+	b         *manager.Broadcaster
+	ratings   *accessor.Ratings
+	gist      *accessor.GistAccessor
+	channelID string
+	refresh   time.Duration
+	log       *slog.Logger
+	changed   chan struct{}
+
+	mu        sync.Mutex
+	messageID string
+}
+
+func newNowPlayingPanel(s *discordgo.Session, b *manager.Broadcaster, ratings *accessor.Ratings, gist *accessor.GistAccessor, channelID string, refresh time.Duration, log *slog.Logger) *nowPlayingPanel {
+	p := &nowPlayingPanel{
+		s:         s,
+		b:         b,
+		ratings:   ratings,
+		gist:      gist,
+		channelID: channelID,
+		refresh:   refresh,
+		log:       log,
+		changed:   make(chan struct{}, 1),
+	}
+	b.OnSongChange(func(accessor.Song) {
+		select {
+		case p.changed <- struct{}{}:
+		default:
+		}
+	})
+	return p
+}
+
+// run redraws the panel on every song change and every refresh interval
+// (for progress), starting from the panel saved before a restart. It
+// returns once done is closed.
+func (p *nowPlayingPanel) run(done <-chan struct{}) {
+	if ref, err := p.gist.LoadPanel(); err != nil {
+		p.log.Warn("load now-playing panel failed; posting a new one", "err", err)
+	} else if ref.ChannelID == p.channelID {
+		p.messageID = ref.MessageID
+	}
+	t := time.NewTicker(p.refresh)
+	defer t.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-p.changed:
+		case <-t.C:
+		}
+		p.update()
+	}
+}
+
+// update edits the panel message, posting a new one if there is none yet or it was deleted.
+func (p *nowPlayingPanel) update() {
+	np := p.b.NowPlaying()
+	if np.Song.ID == "" {
+		return
+	}
+	embed := nowPlayingEmbed(np)
+	likes, dislikes := p.ratings.Counts(np.Song.ID)
+	embed.Footer.Text += fmt.Sprintf(" · 👍 %d 👎 %d", likes, dislikes)
+	components := nowPlayingComponents(np.Song.ID)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.messageID != "" {
+		_, err := p.s.ChannelMessageEditComplex(&discordgo.MessageEdit{
+			ID:         p.messageID,
+			Channel:    p.channelID,
+			Embeds:     &[]*discordgo.MessageEmbed{embed},
+			Components: &components,
+		})
+		if err == nil {
+			return
+		}
+		p.log.Warn("edit now-playing panel failed; posting a new one", "err", err)
+	}
+
+	msg, err := p.s.ChannelMessageSendComplex(p.channelID, &discordgo.MessageSend{
+		Embeds:     []*discordgo.MessageEmbed{embed},
+		Components: components,
+	})
+	if err != nil {
+		p.log.Error("post now-playing panel failed", "err", err)
+		return
+	}
+	p.messageID = msg.ID
+	p.gist.SavePanel(accessor.PanelRef{ChannelID: p.channelID, MessageID: msg.ID})
+}
+
+func nowPlayingEmbed(np manager.NowPlaying) *discordgo.MessageEmbed {
+	e := &discordgo.MessageEmbed{
+		Author:    &discordgo.MessageEmbedAuthor{Name: "🎶 Now playing"},
+		Title:     np.Song.Name,
+		URL:       "https://youtu.be/" + np.Song.ID,
+		Thumbnail: &discordgo.MessageEmbedThumbnail{URL: "https://i.ytimg.com/vi/" + np.Song.ID + "/hqdefault.jpg"},
+		Fields: []*discordgo.MessageEmbedField{
+			{Name: "Artist", Value: orDash(np.Song.Artist)},
+			{Name: "Progress", Value: fmt.Sprintf("%s `%s / %s`",
+				progressBar(np.Position, np.Length), formatDuration(np.Position), formatDuration(np.Length))},
+		},
+		Footer: &discordgo.MessageEmbedFooter{Text: fmt.Sprintf("%d listening", np.Listeners)},
+	}
+	if np.Interrupt != "" {
+		e.Description = fmt.Sprintf("🔴 **On hold for:** %s", np.Interrupt)
+	}
+	if np.Paused {
+		e.Author.Name = "⏸️ Paused"
+		if !np.ResumeAt.IsZero() {
+			e.Description = fmt.Sprintf("Resumes <t:%d:R>", np.ResumeAt.Unix())
+		}
+	}
+	if np.Next.ID != "" {
+		e.Fields = append(e.Fields, &discordgo.MessageEmbedField{
+			Name:  "Up next",
+			Value: fmt.Sprintf("**%s** — %s", np.Next.Name, np.Next.Artist),
+		})
+	}
+	return e
+}
+
+func nowPlayingComponents(songID string) []discordgo.MessageComponent {
+	return []discordgo.MessageComponent{
+		discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+			discordgo.Button{Label: "Skip", Style: discordgo.SecondaryButton, CustomID: npSkip, Emoji: &discordgo.ComponentEmoji{Name: "⏭️"}},
+			discordgo.Button{Label: "Vote skip", Style: discordgo.PrimaryButton, CustomID: npVote + songID, Emoji: &discordgo.ComponentEmoji{Name: "🗳️"}},
+			discordgo.Button{Label: "Remove", Style: discordgo.DangerButton, CustomID: npRemove, Emoji: &discordgo.ComponentEmoji{Name: "🗑️"}},
+		}},
+		discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+			discordgo.Button{Style: discordgo.SuccessButton, CustomID: npLike, Emoji: &discordgo.ComponentEmoji{Name: "👍"}},
+			discordgo.Button{Style: discordgo.SecondaryButton, CustomID: npDislike, Emoji: &discordgo.ComponentEmoji{Name: "👎"}},
+			discordgo.Button{Style: discordgo.SecondaryButton, CustomID: npFavorite, Emoji: &discordgo.ComponentEmoji{Name: "⭐"}},
+		}},
+	}
+}
+
+// progressBar draws a fixed-width bar with a knob at pos/length.
+func progressBar(pos, length time.Duration) string {
+	knob := 0
+	if length > 0 {
+		knob = int(float64(progressBarWidth-1) * float64(pos) / float64(length))
+	}
+	if knob >= progressBarWidth {
+		knob = progressBarWidth - 1
+	}
+	return strings.Repeat("▬", knob) + "🔘" + strings.Repeat("▬", progressBarWidth-1-knob)
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "—"
+	}
+	return s
+}
+
+// handleNowPlayingButton acts on a panel button; replies are ephemeral so the panel stays intact.
+func handleNowPlayingButton(s *discordgo.Session, i *discordgo.InteractionCreate, b *manager.Broadcaster, gist *accessor.GistAccessor, pl *accessor.Playlist, perms *accessor.Permissions) {
+	id := i.MessageComponentData().CustomID
+	songID := ""
+	if rest, ok := strings.CutPrefix(id, npVote); ok {
+		id, songID = npVote, rest
+	}
+	switch id {
+	case npSkip:
+		if !checkPermission(s, i, perms, "skip") {
+			return
+		}
+		b.Skip()
+		respond(s, i, "⏭️ Skipped current track.", true)
+
+	case npVote:
+		if !checkPermission(s, i, perms, "voteskip") {
+			return
+		}
+		t, err := b.VoteSkip("discord:"+interactionUser(i), songID)
+		switch {
+		case err != nil:
+			respond(s, i, "❌ "+err.Error(), true)
+		case !t.Counted:
+			respond(s, i, fmt.Sprintf("You already voted (%d/%d).", t.Votes, t.Needed), true)
+		default:
+			content, _ := voteSkipMessage(t)
+			respond(s, i, content, true)
+		}
+
+	case npRemove:
+		if !checkPermission(s, i, perms, "deletecurrent") {
+			return
+		}
+		if err := b.DeleteCurrent(); err != nil {
+			respond(s, i, "❌ "+err.Error(), true)
+			return
+		}
+		// acknowledge before the Gist save, which can outlast Discord's deadline
+		s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseDeferredMessageUpdate,
+		})
+		go func() {
+			content := "✅ Removed the current track. Use `/undo` to put it back."
+			if err := gist.SavePlaylist(pl); err != nil {
+				content = fmt.Sprintf("❌ Track removed - but Save failed: %v", err)
+			}
+			s.FollowupMessageCreate(i.Interaction, false, &discordgo.WebhookParams{
+				Content: content,
+				Flags:   discordgo.MessageFlagsEphemeral,
+			})
+		}()
+	}
+}
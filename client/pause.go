@@ -0,0 +1,95 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Coop25/CC-Radio/manager"
+	"github.com/bwmarrin/discordgo"
+)
+
+var pauseCommand = &discordgo.ApplicationCommand{
+	Name:        "pause",
+	Description: "Hold the station at the current position",
+	Options: []*discordgo.ApplicationCommandOption{
+		{Type: discordgo.ApplicationCommandOptionInteger, Name: "minutes", Description: "Resume by itself after this many minutes"},
+	},
+}
+
+var resumeCommand = &discordgo.ApplicationCommand{
+	Name:        "resume",
+	Description: "Continue playing from where the station was paused",
+}
+
+// handlePause implements /pause and /resume.
+func handlePause(s *discordgo.Session, i *discordgo.InteractionCreate, b *manager.Broadcaster) {
+	data := i.ApplicationCommandData()
+	if data.Name == "resume" {
+		if err := b.Resume(); err != nil {
+			respond(s, i, "❌ "+err.Error(), true)
+			return
+		}
+		respond(s, i, "▶️ Resumed.", false)
+		return
+	}
+
+	var d time.Duration
+	if len(data.Options) > 0 {
+		n := data.Options[0].IntValue()
+		if n < 1 {
+			respond(s, i, "❌ minutes must be at least 1.", true)
+			return
+		}
+		d = time.Duration(n) * time.Minute
+	}
+	if err := b.Pause(d); err != nil {
+		respond(s, i, "❌ "+err.Error(), true)
+		return
+	}
+	msg := "⏸️ Paused. Use `/resume` to continue."
+	if _, at := b.Paused(); !at.IsZero() {
+		msg = fmt.Sprintf("⏸️ Paused; resuming <t:%d:R>.", at.Unix())
+	}
+	respond(s, i, msg, false)
+}
+
+// pauseHandler serves POST /control/pause[?minutes=N] and POST /control/resume.
+func pauseHandler(b *manager.Broadcaster, log *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		var err error
+		if r.URL.Path == "/control/resume" {
+			err = b.Resume()
+		} else {
+			var d time.Duration
+			if m := r.FormValue("minutes"); m != "" {
+				n, perr := strconv.Atoi(m)
+				if perr != nil || n < 1 {
+					http.Error(w, "minutes must be a positive integer", http.StatusBadRequest)
+					return
+				}
+				d = time.Duration(n) * time.Minute
+			}
+			err = b.Pause(d)
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		paused, resumeAt := b.Paused()
+		log.Info("pause state changed", "paused", paused)
+		resp := map[string]interface{}{"paused": paused}
+		if !resumeAt.IsZero() {
+			resp["resume_at"] = resumeAt
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(resp)
+	}
+}
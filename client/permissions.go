@@ -0,0 +1,225 @@
+package client
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/Coop25/CC-Radio/accessor"
+	"github.com/bwmarrin/discordgo"
+)
+
+var levelChoices = []*discordgo.ApplicationCommandOptionChoice{
+	{Name: "everyone", Value: string(accessor.LevelEveryone)},
+	{Name: "dj", Value: string(accessor.LevelDJ)},
+	{Name: "admin", Value: string(accessor.LevelAdmin)},
+}
+
+var roleLevelChoices = levelChoices[1:]
+
+var permissionsCommand = &discordgo.ApplicationCommand{
+	Name:        "permissions",
+	Description: "Show or change who may use which command",
+	Options: []*discordgo.ApplicationCommandOption{
+		{
+			Type:        discordgo.ApplicationCommandOptionSubCommand,
+			Name:        "show",
+			Description: "Show DJ/admin roles and the level each command needs",
+		},
+		{
+			Type:        discordgo.ApplicationCommandOptionSubCommand,
+			Name:        "set-command",
+			Description: "Change the level a command requires",
+			Options: []*discordgo.ApplicationCommandOption{
+				{Type: discordgo.ApplicationCommandOptionString, Name: "command", Description: "Command name, without the slash", Required: true},
+				{Type: discordgo.ApplicationCommandOptionString, Name: "level", Description: "Required level", Required: true, Choices: levelChoices},
+			},
+		},
+		{
+			Type:        discordgo.ApplicationCommandOptionSubCommand,
+			Name:        "grant-role",
+			Description: "Give a role DJ or admin rank",
+			Options: []*discordgo.ApplicationCommandOption{
+				{Type: discordgo.ApplicationCommandOptionString, Name: "level", Description: "Rank to grant", Required: true, Choices: roleLevelChoices},
+				{Type: discordgo.ApplicationCommandOptionRole, Name: "role", Description: "The role", Required: true},
+			},
+		},
+		{
+			Type:        discordgo.ApplicationCommandOptionSubCommand,
+			Name:        "revoke-role",
+			Description: "Take DJ or admin rank away from a role",
+			Options: []*discordgo.ApplicationCommandOption{
+				{Type: discordgo.ApplicationCommandOptionString, Name: "level", Description: "Rank to revoke", Required: true, Choices: roleLevelChoices},
+				{Type: discordgo.ApplicationCommandOptionRole, Name: "role", Description: "The role", Required: true},
+			},
+		},
+	},
+}
+
+// defaultMemberPermissions is what Discord shows the command to before our own check runs.
+// Admin commands default to Manage Server; server owners can widen that per role in
+// Server Settings → Integrations.
+func defaultMemberPermissions(l accessor.Level) *int64 {
+	if l == accessor.LevelAdmin {
+		p := int64(discordgo.PermissionManageGuild)
+		return &p
+	}
+	return nil
+}
+
+// memberAccess extracts the caller's roles and whether they manage the server.
+func memberAccess(i *discordgo.InteractionCreate) ([]string, bool) {
+	if i.Member == nil {
+		return nil, false
+	}
+	manager := i.Member.Permissions&(discordgo.PermissionManageGuild|discordgo.PermissionAdministrator) != 0
+	return i.Member.Roles, manager
+}
+
+// checkPermission replies with an ephemeral denial and returns false if the caller may not run command.
+func checkPermission(s *discordgo.Session, i *discordgo.InteractionCreate, perms *accessor.Permissions, command string) bool {
+	roles, manager := memberAccess(i)
+	if perms.Allowed(command, roles, manager) {
+		return true
+	}
+	need := "DJ"
+	if perms.Required(command) == accessor.LevelAdmin {
+		need = "admin"
+	}
+	respond(s, i, fmt.Sprintf("🚫 You need the %s role to use `/%s`.", need, command), true)
+	return false
+}
+
+// respond sends a plain text reply to an interaction.
+func respond(s *discordgo.Session, i *discordgo.InteractionCreate, content string, ephemeral bool) {
+	data := &discordgo.InteractionResponseData{Content: content}
+	if ephemeral {
+		data.Flags = discordgo.MessageFlagsEphemeral
+	}
+	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: data,
+	})
+}
+
+// respondAfter acknowledges i straight away, then runs work and edits its
+// result in: for replies that wait on a Gist save, which can take longer
+// than Discord's 3-second deadline.
+func respondAfter(s *discordgo.Session, i *discordgo.InteractionCreate, ephemeral bool, log *slog.Logger, work func() string) {
+	resp := &discordgo.InteractionResponse{Type: discordgo.InteractionResponseDeferredChannelMessageWithSource}
+	if ephemeral {
+		resp.Data = &discordgo.InteractionResponseData{Flags: discordgo.MessageFlagsEphemeral}
+	}
+	if err := s.InteractionRespond(i.Interaction, resp); err != nil {
+		// the change is made already, so it is saved regardless
+		log.Warn("defer response failed", "err", err)
+	}
+	go func() {
+		content := work()
+		if _, err := s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{Content: &content}); err != nil {
+			log.Warn("edit response failed", "err", err)
+		}
+	}()
+}
+
+// handlePermissions implements the /permissions subcommands.
+func (d *DiscordBot) handlePermissions(s *discordgo.Session, i *discordgo.InteractionCreate, gist *accessor.GistAccessor, perms *accessor.Permissions, log *slog.Logger) {
+	sub := i.ApplicationCommandData().Options[0]
+	opts := make(map[string]*discordgo.ApplicationCommandInteractionDataOption, len(sub.Options))
+	for _, o := range sub.Options {
+		opts[o.Name] = o
+	}
+
+	var after func() // Discord-side follow-up, run with the save
+	switch sub.Name {
+	case "show":
+		names := make([]string, 0, len(commands))
+		for _, c := range commands {
+			names = append(names, c.Name)
+		}
+		respond(s, i, formatPermissions(perms.View(names)), true)
+		return
+
+	case "set-command":
+		name := strings.TrimPrefix(opts["command"].StringValue(), "/")
+		if !d.knownCommand(name) {
+			respond(s, i, fmt.Sprintf("❌ Unknown command %q", name), true)
+			return
+		}
+		level, err := accessor.ParseLevel(opts["level"].StringValue())
+		if err != nil {
+			respond(s, i, "❌ "+err.Error(), true)
+			return
+		}
+		perms.SetRequired(name, level)
+		after = func() { d.syncDefaultPermissions(name, level, log) }
+
+	case "grant-role", "revoke-role":
+		level, err := accessor.ParseLevel(opts["level"].StringValue())
+		if err == nil {
+			err = perms.SetRole(level, opts["role"].RoleValue(nil, "").ID, sub.Name == "grant-role")
+		}
+		if err != nil {
+			respond(s, i, "❌ "+err.Error(), true)
+			return
+		}
+	}
+
+	respondAfter(s, i, true, log, func() string {
+		if after != nil {
+			after()
+		}
+		if err := gist.SavePermissions(perms); err != nil {
+			return fmt.Sprintf("❌ Permissions changed - but Save failed: %v", err)
+		}
+		return "✅ Permissions updated."
+	})
+}
+
+func (d *DiscordBot) knownCommand(name string) bool {
+	for _, c := range commands {
+		if c.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// syncDefaultPermissions re-registers a command's Discord-side default after its level changes.
+func (d *DiscordBot) syncDefaultPermissions(name string, level accessor.Level, log *slog.Logger) {
+	id, ok := d.cmdIDs[name]
+	if !ok {
+		return
+	}
+	for _, c := range commands {
+		if c.Name != name {
+			continue
+		}
+		edit := *c
+		edit.DefaultMemberPermissions = defaultMemberPermissions(level)
+		if _, err := d.ApplicationCommandEdit(d.appID, d.guildID, id, &edit); err != nil {
+			log.Warn("could not update command default permissions", "command", name, "err", err)
+		}
+		return
+	}
+}
+
+func formatPermissions(v accessor.PermissionsView) string {
+	roles := func(ids []string) string {
+		if len(ids) == 0 {
+			return "_none_"
+		}
+		out := make([]string, len(ids))
+		for i, id := range ids {
+			out[i] = "<@&" + id + ">"
+		}
+		return strings.Join(out, ", ")
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "**DJ roles:** %s\n**Admin roles:** %s\n\n", roles(v.DJRoles), roles(v.AdminRoles))
+	for _, c := range commands {
+		fmt.Fprintf(&sb, "`/%s` — %s\n", c.Name, v.Commands[c.Name])
+	}
+	return sb.String()
+}
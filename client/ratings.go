@@ -0,0 +1,132 @@
+package client
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Coop25/CC-Radio/accessor"
+	"github.com/Coop25/CC-Radio/manager"
+	"github.com/bwmarrin/discordgo"
+)
+
+// panel buttons for rating the current song
+const (
+	npLike     = npButtonPrefix + "like"
+	npDislike  = npButtonPrefix + "dislike"
+	npFavorite = npButtonPrefix + "favorite"
+)
+
+var rateChoices = []*discordgo.ApplicationCommandOptionChoice{
+	{Name: "👍 like", Value: 1},
+	{Name: "👎 dislike", Value: -1},
+	{Name: "clear", Value: 0},
+}
+
+// rateCurrent applies a Discord user's rating to whatever is on air.
+func rateCurrent(b *manager.Broadcaster, ratings *accessor.Ratings, user string, value int) (accessor.Song, int, error) {
+	song := b.NowPlaying().Song
+	if song.ID == "" {
+		return song, 0, fmt.Errorf("nothing is playing")
+	}
+	return song, ratings.Rate(song.ID, "discord:"+user, value), nil
+}
+
+// handleRatingCommand implements /rate, /favorite and /favorites.
+func handleRatingCommand(s *discordgo.Session, i *discordgo.InteractionCreate, b *manager.Broadcaster, pl *accessor.Playlist, ratings *accessor.Ratings) {
+	data := i.ApplicationCommandData()
+	user := interactionUser(i)
+
+	switch data.Name {
+	case "rate":
+		value := int(data.Options[0].IntValue())
+		song, score, err := rateCurrent(b, ratings, user, value)
+		if err != nil {
+			respond(s, i, "❌ "+err.Error(), true)
+			return
+		}
+		respond(s, i, fmt.Sprintf("%s **%s** (score %+d)", rateVerb(value), song.Name, score), true)
+
+	case "favorite":
+		song := b.NowPlaying().Song
+		if song.ID == "" {
+			respond(s, i, "❌ nothing is playing", true)
+			return
+		}
+		respond(s, i, favoriteMessage(song, ratings.ToggleFavorite(user, song.ID)), true)
+
+	case "favorites":
+		if len(data.Options) > 0 {
+			user = data.Options[0].UserValue(nil).ID
+		}
+		respond(s, i, formatFavorites(pl, ratings.Favorites(user)), true)
+	}
+}
+
+// handleRatingButton acts on the like/dislike/favorite panel buttons.
+func handleRatingButton(s *discordgo.Session, i *discordgo.InteractionCreate, b *manager.Broadcaster, ratings *accessor.Ratings) {
+	user := interactionUser(i)
+	switch id := i.MessageComponentData().CustomID; id {
+	case npLike, npDislike:
+		value := 1
+		if id == npDislike {
+			value = -1
+		}
+		song, score, err := rateCurrent(b, ratings, user, value)
+		if err != nil {
+			respond(s, i, "❌ "+err.Error(), true)
+			return
+		}
+		respond(s, i, fmt.Sprintf("%s **%s** (score %+d)", rateVerb(value), song.Name, score), true)
+
+	case npFavorite:
+		song := b.NowPlaying().Song
+		if song.ID == "" {
+			respond(s, i, "❌ nothing is playing", true)
+			return
+		}
+		respond(s, i, favoriteMessage(song, ratings.ToggleFavorite(user, song.ID)), true)
+	}
+}
+
+func rateVerb(value int) string {
+	switch {
+	case value > 0:
+		return "👍 Liked"
+	case value < 0:
+		return "👎 Disliked"
+	}
+	return "Cleared your rating of"
+}
+
+func favoriteMessage(song accessor.Song, added bool) string {
+	if added {
+		return fmt.Sprintf("⭐ Added **%s** to your favorites.", song.Name)
+	}
+	return fmt.Sprintf("Removed **%s** from your favorites.", song.Name)
+}
+
+// formatFavorites resolves favorite IDs against the library, noting removed tracks.
+func formatFavorites(pl *accessor.Playlist, ids []string) string {
+	if len(ids) == 0 {
+		return "No favorites yet — press ⭐ on the now-playing panel or use `/favorite`."
+	}
+	byID := make(map[string]accessor.Song)
+	for _, s := range pl.Songs() {
+		byID[s.ID] = s
+	}
+
+	var sb strings.Builder
+	sb.WriteString("⭐ **Favorites**\n")
+	for n, id := range ids {
+		if n == 20 {
+			fmt.Fprintf(&sb, "…and %d more", len(ids)-n)
+			break
+		}
+		if s, ok := byID[id]; ok {
+			fmt.Fprintf(&sb, "• **%s** — %s\n", s.Name, s.Artist)
+		} else {
+			fmt.Fprintf(&sb, "• `%s` _(no longer in the library)_\n", id)
+		}
+	}
+	return sb.String()
+}
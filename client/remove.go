@@ -0,0 +1,92 @@
+package client
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/Coop25/CC-Radio/accessor"
+	"github.com/bwmarrin/discordgo"
+)
+
+// how many matched titles a bulk-removal reply lists before summarising
+const removalPreviewLimit = 15
+
+// handleRemove implements /removesong, /remove-radio-segment, /removematching and /undo.
+// Mistakes are answered at once; a change is acknowledged before it is saved.
+func handleRemove(s *discordgo.Session, i *discordgo.InteractionCreate, gist *accessor.GistAccessor, pl *accessor.Playlist, log *slog.Logger) {
+	data := i.ApplicationCommandData()
+	opts := make(map[string]*discordgo.ApplicationCommandInteractionDataOption, len(data.Options))
+	for _, o := range data.Options {
+		opts[o.Name] = o
+	}
+
+	var (
+		r    accessor.Removal
+		err  error
+		verb = "Removed"
+	)
+	switch data.Name {
+	case "removesong":
+		r, err = pl.RemoveSong(opts["song"].StringValue())
+	case "remove-radio-segment":
+		r, err = pl.RemoveRadio(opts["segment"].StringValue())
+	case "removematching":
+		pattern := opts["pattern"].StringValue()
+		radio := opts["list"] != nil && opts["list"].StringValue() == "radio"
+		if opts["confirm"] == nil || !opts["confirm"].BoolValue() {
+			matches, err := pl.Matching(pattern, radio)
+			if err != nil {
+				respond(s, i, "❌ "+err.Error(), true)
+				return
+			}
+			respond(s, i, previewRemoval(pattern, matches), true)
+			return
+		}
+		r, err = pl.RemoveMatching(pattern, radio)
+	case "undo":
+		r, err = pl.Undo()
+		verb = "Restored"
+	}
+	if err != nil {
+		respond(s, i, "❌ "+err.Error(), true)
+		return
+	}
+	log.Info("playlist changed", "action", strings.ToLower(verb), "songs", len(r.Songs), "radio", len(r.Radio))
+
+	respondAfter(s, i, false, log, func() string {
+		if err := gist.SavePlaylist(pl); err != nil {
+			return fmt.Sprintf("❌ %s %d track(s) - but Save failed: %v", verb, r.Len(), err)
+		}
+		msg := fmt.Sprintf("✅ %s %s", verb, describeRemoval(r))
+		if verb == "Removed" {
+			msg += "\nUse `/undo` to put it back."
+		}
+		return msg
+	})
+}
+
+func describeRemoval(r accessor.Removal) string {
+	all := append(append([]accessor.Song(nil), r.Songs...), r.Radio...)
+	if len(all) == 1 {
+		return fmt.Sprintf("**%s** — %s", all[0].Name, all[0].Artist)
+	}
+	return fmt.Sprintf("%d tracks", len(all))
+}
+
+func previewRemoval(pattern string, songs []accessor.Song) string {
+	if len(songs) == 0 {
+		return fmt.Sprintf("No tracks match %q.", pattern)
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%d track(s) match %q:\n", len(songs), pattern)
+	for n, s := range songs {
+		if n == removalPreviewLimit {
+			fmt.Fprintf(&sb, "…and %d more\n", len(songs)-n)
+			break
+		}
+		fmt.Fprintf(&sb, "• **%s** — %s\n", s.Name, s.Artist)
+	}
+	sb.WriteString("Run again with `confirm:True` to remove them.")
+	return sb.String()
+}
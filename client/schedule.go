@@ -0,0 +1,150 @@
+package client
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/Coop25/CC-Radio/accessor"
+	"github.com/bwmarrin/discordgo"
+)
+
+var scheduleCommand = &discordgo.ApplicationCommand{
+	Name:        "schedule",
+	Description: "Show the programming block on air and what's on next",
+}
+
+var scheduleEditCommand = &discordgo.ApplicationCommand{
+	Name:        "schedule-edit",
+	Description: "Add, change or remove programming blocks",
+	Options: []*discordgo.ApplicationCommandOption{
+		{
+			Type:        discordgo.ApplicationCommandOptionSubCommand,
+			Name:        "add",
+			Description: "Add a block, or replace the block with the same name",
+			Options: []*discordgo.ApplicationCommandOption{
+				{Type: discordgo.ApplicationCommandOptionString, Name: "name", Description: "Block name, e.g. Rock hour", Required: true},
+				{Type: discordgo.ApplicationCommandOptionString, Name: "days", Description: "daily, weekdays, weekends, mon-fri, sat,sun …", Required: true},
+				{Type: discordgo.ApplicationCommandOptionString, Name: "start", Description: "Start time, HH:MM", Required: true},
+				{Type: discordgo.ApplicationCommandOptionString, Name: "end", Description: "End time, HH:MM", Required: true},
+				{Type: discordgo.ApplicationCommandOptionString, Name: "pattern", Description: "Only play songs whose name or artist matches (e.g. *rock*)"},
+				{Type: discordgo.ApplicationCommandOptionString, Name: "tags", Description: `Only play songs matching a tag expression (e.g. "rock and not live")`},
+				{Type: discordgo.ApplicationCommandOptionInteger, Name: "radio-cooldown", Description: "Minutes between radio segments during the block"},
+			},
+		},
+		{
+			Type:        discordgo.ApplicationCommandOptionSubCommand,
+			Name:        "remove",
+			Description: "Remove a block",
+			Options: []*discordgo.ApplicationCommandOption{
+				{Type: discordgo.ApplicationCommandOptionString, Name: "name", Description: "Block name", Required: true},
+			},
+		},
+	},
+}
+
+// handleSchedule shows the block on air now, the next few starts and the radio-segment rules.
+func handleSchedule(s *discordgo.Session, i *discordgo.InteractionCreate, sched *accessor.Schedule, pl *accessor.Playlist) {
+	now := time.Now()
+	var sb strings.Builder
+	if b, ok := sched.Active(now); ok {
+		fmt.Fprintf(&sb, "🔴 **On air:** %s (until %s)\n", b.Name, b.End)
+	} else {
+		sb.WriteString("🔴 **On air:** regular rotation\n")
+	}
+
+	upcoming := sched.Upcoming(now)
+	if len(upcoming) > 5 {
+		upcoming = upcoming[:5]
+	}
+	if len(upcoming) == 0 {
+		sb.WriteString("\nNo programming blocks are scheduled.\n")
+	} else {
+		sb.WriteString("\n**Coming up:**\n")
+		for _, u := range upcoming {
+			fmt.Fprintf(&sb, "• <t:%d:F> (<t:%d:R>) — **%s** until %s · %s\n",
+				u.StartsAt.Unix(), u.StartsAt.Unix(), u.Name, u.End, blockSummary(u.Block))
+		}
+		fmt.Fprintf(&sb, "_Block times are in %s._\n", sched.Location())
+	}
+
+	if rules := pl.SegmentRules(); len(rules) > 0 {
+		sb.WriteString("\n**Radio segments:**\n")
+		for _, r := range rules {
+			sb.WriteString("• " + r + "\n")
+		}
+	}
+	respond(s, i, sb.String(), false)
+}
+
+// handleScheduleEdit implements the /schedule-edit subcommands and saves the result.
+func handleScheduleEdit(s *discordgo.Session, i *discordgo.InteractionCreate, gist *accessor.GistAccessor, sched *accessor.Schedule, pl *accessor.Playlist, log *slog.Logger) {
+	sub := i.ApplicationCommandData().Options[0]
+	opts := make(map[string]*discordgo.ApplicationCommandInteractionDataOption, len(sub.Options))
+	for _, o := range sub.Options {
+		opts[o.Name] = o
+	}
+
+	var msg string
+	switch sub.Name {
+	case "add":
+		b := accessor.Block{
+			Name:  opts["name"].StringValue(),
+			Days:  opts["days"].StringValue(),
+			Start: opts["start"].StringValue(),
+			End:   opts["end"].StringValue(),
+		}
+		if o, ok := opts["pattern"]; ok {
+			b.Pattern = o.StringValue()
+		}
+		if o, ok := opts["tags"]; ok {
+			b.Tags = o.StringValue()
+		}
+		if o, ok := opts["radio-cooldown"]; ok {
+			b.RadioCooldownMinutes = int(o.IntValue())
+		}
+		if err := sched.Put(b); err != nil {
+			respond(s, i, "❌ "+err.Error(), true)
+			return
+		}
+		msg = fmt.Sprintf("✅ Scheduled **%s**: %s %s–%s · %s", b.Name, b.Days, b.Start, b.End, blockSummary(b))
+	case "remove":
+		name := opts["name"].StringValue()
+		if err := sched.Delete(name); err != nil {
+			respond(s, i, "❌ "+err.Error(), true)
+			return
+		}
+		msg = fmt.Sprintf("🗑️ Removed block **%s**.", name)
+	}
+
+	pl.Replan()
+	respondAfter(s, i, false, log, func() string {
+		if err := gist.SaveSchedule(sched); err != nil {
+			log.Warn("command failed", "err", err)
+			msg += fmt.Sprintf("\n⚠️ Save failed: %v", err)
+		}
+		return msg
+	})
+}
+
+// blockSummary describes what a block plays.
+func blockSummary(b accessor.Block) string {
+	var parts []string
+	switch {
+	case len(b.Songs) > 0:
+		parts = append(parts, fmt.Sprintf("%d picked songs", len(b.Songs)))
+	case b.Pattern != "" && b.Tags != "":
+		parts = append(parts, fmt.Sprintf("songs matching `%s` tagged `%s`", b.Pattern, b.Tags))
+	case b.Pattern != "":
+		parts = append(parts, fmt.Sprintf("songs matching `%s`", b.Pattern))
+	case b.Tags != "":
+		parts = append(parts, fmt.Sprintf("songs tagged `%s`", b.Tags))
+	default:
+		parts = append(parts, "full library")
+	}
+	if b.RadioCooldownMinutes > 0 {
+		parts = append(parts, fmt.Sprintf("radio every %dm", b.RadioCooldownMinutes))
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,120 @@
+package client
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Coop25/CC-Radio/manager"
+	"github.com/bwmarrin/discordgo"
+)
+
+// defaultSeekStep is how far /forward and /rewind move without a seconds option.
+const defaultSeekStep = 10 * time.Second
+
+var seekCommand = &discordgo.ApplicationCommand{
+	Name:        "seek",
+	Description: "Jump to a position in the current track",
+	Options: []*discordgo.ApplicationCommandOption{
+		{Type: discordgo.ApplicationCommandOptionString, Name: "position", Description: "mm:ss (or h:mm:ss, or seconds)", Required: true},
+	},
+}
+
+var forwardCommand = &discordgo.ApplicationCommand{
+	Name:        "forward",
+	Description: "Skip ahead in the current track",
+	Options: []*discordgo.ApplicationCommandOption{
+		{Type: discordgo.ApplicationCommandOptionInteger, Name: "seconds", Description: "How far (default 10)"},
+	},
+}
+
+var rewindCommand = &discordgo.ApplicationCommand{
+	Name:        "rewind",
+	Description: "Go back in the current track",
+	Options: []*discordgo.ApplicationCommandOption{
+		{Type: discordgo.ApplicationCommandOptionInteger, Name: "seconds", Description: "How far (default 10)"},
+	},
+}
+
+// handleSeek implements /seek, /forward and /rewind.
+func handleSeek(s *discordgo.Session, i *discordgo.InteractionCreate, b *manager.Broadcaster) {
+	data := i.ApplicationCommandData()
+	var (
+		pos time.Duration
+		err error
+	)
+	switch data.Name {
+	case "seek":
+		target, perr := parseTimestamp(data.Options[0].StringValue())
+		if perr != nil {
+			respond(s, i, "❌ "+perr.Error(), true)
+			return
+		}
+		pos, err = b.Seek(target)
+	case "forward", "rewind":
+		step := defaultSeekStep
+		if len(data.Options) > 0 {
+			step = time.Duration(data.Options[0].IntValue()) * time.Second
+		}
+		if step <= 0 {
+			respond(s, i, "❌ seconds must be at least 1.", true)
+			return
+		}
+		if data.Name == "rewind" {
+			step = -step
+		}
+		pos, err = b.SeekBy(step)
+	}
+	if err != nil {
+		respond(s, i, "❌ "+err.Error(), true)
+		return
+	}
+	np := b.NowPlaying()
+	respond(s, i, fmt.Sprintf("⏩ **%s** at `%s / %s`", np.Song.Name, formatDuration(pos), formatDuration(np.Length)), false)
+}
+
+// parseTimestamp accepts "ss", "mm:ss" or "h:mm:ss".
+func parseTimestamp(ts string) (time.Duration, error) {
+	parts := strings.Split(strings.TrimSpace(ts), ":")
+	if len(parts) > 3 {
+		return 0, fmt.Errorf("invalid position %q (want mm:ss)", ts)
+	}
+	var total time.Duration
+	for n, p := range parts {
+		v, err := strconv.Atoi(p)
+		if err != nil || v < 0 || (n > 0 && v > 59) {
+			return 0, fmt.Errorf("invalid position %q (want mm:ss)", ts)
+		}
+		total = total*60 + time.Duration(v)
+	}
+	return total * time.Second, nil
+}
+
+var replayCommand = &discordgo.ApplicationCommand{
+	Name:        "replay",
+	Description: "Restart the current track",
+}
+
+var previousCommand = &discordgo.ApplicationCommand{
+	Name:        "previous",
+	Description: "Go back to the track before this one; what's up next stays the same",
+}
+
+// handleReplay implements /replay and /previous.
+func handleReplay(s *discordgo.Session, i *discordgo.InteractionCreate, b *manager.Broadcaster) {
+	if i.ApplicationCommandData().Name == "replay" {
+		if err := b.Replay(); err != nil {
+			respond(s, i, "❌ "+err.Error(), true)
+			return
+		}
+		respond(s, i, fmt.Sprintf("🔁 Restarted **%s**.", b.NowPlaying().Song.Name), false)
+		return
+	}
+	song, err := b.Previous()
+	if err != nil {
+		respond(s, i, "❌ "+err.Error(), true)
+		return
+	}
+	respond(s, i, fmt.Sprintf("⏮️ Going back to **%s** — %s.", song.Name, song.Artist), false)
+}
@@ -0,0 +1,178 @@
+package client
+
+import (
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+
+	"github.com/Coop25/CC-Radio/accessor"
+	"github.com/bwmarrin/discordgo"
+)
+
+var tagCommand = &discordgo.ApplicationCommand{
+	Name:        "tag",
+	Description: "Add tags (genre, mood, source…) to a song or radio segment",
+	Options: []*discordgo.ApplicationCommandOption{
+		{Type: discordgo.ApplicationCommandOptionString, Name: "song", Description: "Song or radio segment to tag", Required: true, Autocomplete: true},
+		{Type: discordgo.ApplicationCommandOptionString, Name: "tags", Description: "Comma-separated, e.g. rock, chill", Required: true},
+	},
+}
+
+var untagCommand = &discordgo.ApplicationCommand{
+	Name:        "untag",
+	Description: "Remove tags from a song or radio segment",
+	Options: []*discordgo.ApplicationCommandOption{
+		{Type: discordgo.ApplicationCommandOptionString, Name: "song", Description: "Song or radio segment to untag", Required: true, Autocomplete: true},
+		{Type: discordgo.ApplicationCommandOptionString, Name: "tags", Description: "Comma-separated", Required: true},
+	},
+}
+
+var bulkTagCommand = &discordgo.ApplicationCommand{
+	Name:        "bulktag",
+	Description: "Tag every master-list song whose name or artist matches a pattern",
+	Options: []*discordgo.ApplicationCommandOption{
+		{Type: discordgo.ApplicationCommandOptionString, Name: "pattern", Description: "Text or glob (e.g. *live*)", Required: true},
+		{Type: discordgo.ApplicationCommandOptionString, Name: "tags", Description: "Comma-separated", Required: true},
+	},
+}
+
+var tagsCommand = &discordgo.ApplicationCommand{
+	Name:        "tags",
+	Description: "List every tag and how many songs carry it",
+}
+
+var rotationFilterCommand = &discordgo.ApplicationCommand{
+	Name:        "rotation-filter",
+	Description: "Show or set the tag expression the station rotation plays",
+	Options: []*discordgo.ApplicationCommandOption{
+		{Type: discordgo.ApplicationCommandOptionString, Name: "expression", Description: `e.g. "rock and not live"; "off" clears it`},
+	},
+}
+
+// handleTagCommand implements /tag, /untag, /bulktag, /tags and /rotation-filter.
+// Changes are saved to the Gist straight away.
+func handleTagCommand(s *discordgo.Session, i *discordgo.InteractionCreate, gist *accessor.GistAccessor, pl *accessor.Playlist, log *slog.Logger) {
+	data := i.ApplicationCommandData()
+	opts := make(map[string]*discordgo.ApplicationCommandInteractionDataOption, len(data.Options))
+	for _, o := range data.Options {
+		opts[o.Name] = o
+	}
+
+	var msg string
+	switch data.Name {
+	case "tag", "untag":
+		tags := accessor.ParseTags(opts["tags"].StringValue())
+		if len(tags) == 0 {
+			respond(s, i, "❌ No tags given.", true)
+			return
+		}
+		id := opts["song"].StringValue()
+		var (
+			song accessor.Song
+			err  error
+		)
+		if data.Name == "tag" {
+			song, err = pl.Tag(id, tags)
+		} else {
+			song, err = pl.Untag(id, tags)
+		}
+		if err != nil {
+			respond(s, i, "❌ "+err.Error(), true)
+			return
+		}
+		msg = "🏷️ " + songLine(song)
+
+	case "bulktag":
+		tags := accessor.ParseTags(opts["tags"].StringValue())
+		if len(tags) == 0 {
+			respond(s, i, "❌ No tags given.", true)
+			return
+		}
+		pattern := opts["pattern"].StringValue()
+		n, err := pl.BulkTag(pattern, tags)
+		if err != nil {
+			respond(s, i, "❌ "+err.Error(), true)
+			return
+		}
+		if n == 0 {
+			respond(s, i, fmt.Sprintf("❌ No songs match `%s`.", pattern), true)
+			return
+		}
+		msg = fmt.Sprintf("🏷️ Tagged %d songs matching `%s` with %s.", n, pattern, formatTags(tags))
+
+	case "tags":
+		respond(s, i, formatTagCounts(pl.TagCounts(), pl.RotationFilter()), false)
+		return
+
+	case "rotation-filter":
+		o, ok := opts["expression"]
+		if !ok {
+			if f := pl.RotationFilter(); f != "" {
+				respond(s, i, fmt.Sprintf("🎛️ Rotation filter: `%s`", f), true)
+			} else {
+				respond(s, i, "🎛️ No rotation filter; the whole library is in rotation.", true)
+			}
+			return
+		}
+		expr := o.StringValue()
+		if strings.EqualFold(strings.TrimSpace(expr), "off") {
+			expr = ""
+		}
+		if err := pl.SetRotationFilter(expr); err != nil {
+			respond(s, i, "❌ "+err.Error(), true)
+			return
+		}
+		if expr == "" {
+			msg = "🎛️ Rotation filter cleared; the whole library is in rotation."
+		} else {
+			msg = fmt.Sprintf("🎛️ Rotation now plays songs matching `%s`.", expr)
+		}
+	}
+
+	respondAfter(s, i, false, log, func() string {
+		if err := gist.SavePlaylist(pl); err != nil {
+			log.Warn("command failed", "err", err)
+			msg += fmt.Sprintf("\n⚠️ Save failed: %v", err)
+		}
+		return msg
+	})
+}
+
+func formatTags(tags []string) string {
+	out := make([]string, len(tags))
+	for n, t := range tags {
+		out[n] = "`#" + t + "`"
+	}
+	return strings.Join(out, " ")
+}
+
+func formatTagCounts(counts map[string]int, filter string) string {
+	if len(counts) == 0 {
+		return "No songs are tagged yet. Use `/tag` or `/bulktag`."
+	}
+	tags := make([]string, 0, len(counts))
+	for t := range counts {
+		tags = append(tags, t)
+	}
+	sort.Slice(tags, func(a, b int) bool {
+		if counts[tags[a]] != counts[tags[b]] {
+			return counts[tags[a]] > counts[tags[b]]
+		}
+		return tags[a] < tags[b]
+	})
+	var sb strings.Builder
+	sb.WriteString("🏷️ **Tags**\n")
+	for _, t := range tags {
+		line := fmt.Sprintf("`#%s` × %d\n", t, counts[t])
+		if sb.Len()+len(line) > 1800 {
+			sb.WriteString("…\n")
+			break
+		}
+		sb.WriteString(line)
+	}
+	if filter != "" {
+		fmt.Fprintf(&sb, "\nRotation filter: `%s`", filter)
+	}
+	return sb.String()
+}
@@ -0,0 +1,65 @@
+package client
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Coop25/CC-Radio/manager"
+	"github.com/bwmarrin/discordgo"
+)
+
+// voteSkipButtonPrefix starts the custom ID of the button on /voteskip
+// messages; the rest is the song the vote is about, so a click on an old
+// message can't count against whatever is playing now.
+const voteSkipButtonPrefix = "voteskip:"
+
+// voteSkipMessage renders the tally plus a vote button (disabled once the vote passed).
+func voteSkipMessage(t manager.VoteTally) (string, []discordgo.MessageComponent) {
+	content := fmt.Sprintf("🗳️ Skip **%s**? %d/%d votes", t.SongName, t.Votes, t.Needed)
+	if t.Skipped {
+		content = fmt.Sprintf("⏭️ Vote passed (%d/%d) — skipped **%s**.", t.Votes, t.Needed, t.SongName)
+	}
+	return content, []discordgo.MessageComponent{
+		discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+			discordgo.Button{
+				Label:    "Vote to skip",
+				Style:    discordgo.PrimaryButton,
+				CustomID: voteSkipButtonPrefix + t.SongID,
+				Emoji:    &discordgo.ComponentEmoji{Name: "⏭️"},
+				Disabled: t.Skipped,
+			},
+		}},
+	}
+}
+
+// handleVoteSkip casts the caller's vote from /voteskip (a new message) or the
+// button (editing the message in place).
+func handleVoteSkip(s *discordgo.Session, i *discordgo.InteractionCreate, b *manager.Broadcaster) {
+	songID := ""
+	if i.Type == discordgo.InteractionMessageComponent {
+		songID = strings.TrimPrefix(i.MessageComponentData().CustomID, voteSkipButtonPrefix)
+	}
+	t, err := b.VoteSkip("discord:"+interactionUser(i), songID)
+	if err != nil {
+		respond(s, i, "❌ "+err.Error(), true)
+		return
+	}
+
+	respType := discordgo.InteractionResponseChannelMessageWithSource
+	if i.Type == discordgo.InteractionMessageComponent {
+		if !t.Counted {
+			respond(s, i, "You already voted to skip this song.", true)
+			return
+		}
+		respType = discordgo.InteractionResponseUpdateMessage
+	}
+
+	content, components := voteSkipMessage(t)
+	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: respType,
+		Data: &discordgo.InteractionResponseData{
+			Content:    content,
+			Components: components,
+		},
+	})
+}
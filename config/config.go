@@ -1,6 +1,7 @@
 package config
 
 import (
+	"fmt"
 	"time"
 
 	"github.com/kelseyhightower/envconfig"
@@ -10,19 +11,63 @@ type Config struct {
 	HTTPPort        int           `envconfig:"PORT"        default:"8080"`
 	ChunkInterval   time.Duration `envconfig:"CHUNK_INTERVAL" default:"100ms"`
 	RandomCooldown  time.Duration `envconfig:"RANDOM_COOLDOWN" default:"30m"`
-	RandomMaxChance float64       `envconfig:"RANDOM_MAX_CHANCE" default:"0.1"`
+	RandomMaxChance float64       `envconfig:"RANDOM_MAX_CHANCE" default:"0.1"` // chance, ramping up during the cooldown, of an early segment
+	RadioRules      string        `envconfig:"RADIO_RULES"`                     // JSON segment rules; replaces the two above when set
+	UndoWindow      time.Duration `envconfig:"UNDO_WINDOW" default:"10m"`       // how long /undo can restore a removal
+	RatingBias      float64       `envconfig:"RATING_BIAS" default:"0.2"`       // play-frequency weight per net like or favorite; 0 disables
+	Timezone        string        `envconfig:"TIMEZONE" default:"UTC"`          // zone programming block times are written in
+	Schedule        string        `envconfig:"SCHEDULE"`                        // JSON block list; used until schedule.json exists in the Gist
 
-	FetchBaseURL string `envconfig:"FETCH_BASE_URL" required:"true"`
-	AuthToken    string `envconfig:"FETCH_AUTH_TOKEN"` // optional
+	FetchBaseURL     string        `envconfig:"FETCH_BASE_URL"`                    // single converter; FETCH_BACKENDS replaces it
+	FetchBackends    string        `envconfig:"FETCH_BACKENDS"`                    // JSON [{"url","weight","prefer":"search"|"audio"}]
+	AuthToken        string        `envconfig:"FETCH_AUTH_TOKEN"`                  // optional
+	AudioCacheSize   int           `envconfig:"AUDIO_CACHE_SIZE" default:"8"`      // tracks kept in memory
+	AudioCacheMaxLen time.Duration `envconfig:"AUDIO_CACHE_MAX_LEN" default:"20m"` // longer tracks are streamed without caching
+	StreamBuffer     time.Duration `envconfig:"STREAM_BUFFER" default:"30s"`       // audio read ahead of playback, per track
 
-	GITHUB_TOKEN   string        `envconfig:"GITHUB_TOKEN"     required:"true"`
-	GITHUB_GIST_ID string        `envconfig:"GITHUB_GIST_ID"`
-	SaveInterval   time.Duration `envconfig:"SAVE_INTERVAL" default:"1h"` // how often to auto-save
+	// converter resilience: per-operation timeouts, retries with jittered
+	// exponential backoff, and a breaker that stops calls while it is down
+	FetchSearchTimeout  time.Duration `envconfig:"FETCH_SEARCH_TIMEOUT" default:"30s"` // whole song/playlist lookup
+	FetchAudioTimeout   time.Duration `envconfig:"FETCH_AUDIO_TIMEOUT" default:"10s"`  // wait for headers, or for more bytes mid-stream
+	FetchRetries        int           `envconfig:"FETCH_RETRIES" default:"3"`          // extra attempts after a failure
+	FetchBackoff        time.Duration `envconfig:"FETCH_BACKOFF" default:"500ms"`      // first retry delay, doubled each time
+	FetchBackoffMax     time.Duration `envconfig:"FETCH_BACKOFF_MAX" default:"30s"`
+	BreakerThreshold    int           `envconfig:"BREAKER_THRESHOLD" default:"5"`       // consecutive failures that open the breaker
+	BreakerCooldown     time.Duration `envconfig:"BREAKER_COOLDOWN" default:"30s"`      // how long it stays open before a probe
+	FetchHealthInterval time.Duration `envconfig:"FETCH_HEALTH_INTERVAL" default:"30s"` // how often every endpoint is probed; 0 disables
+
+	GITHUB_TOKEN        string        `envconfig:"GITHUB_TOKEN"     required:"true"`
+	GITHUB_GIST_ID      string        `envconfig:"GITHUB_GIST_ID"`
+	SaveInterval        time.Duration `envconfig:"SAVE_INTERVAL" default:"1h"`         // how often to auto-save
+	HistoryFile         string        `envconfig:"HISTORY_FILE"`                       // optional JSON-lines play log, appended to and reloaded on start
+	RatingsSaveInterval time.Duration `envconfig:"RATINGS_SAVE_INTERVAL" default:"1m"` // how often changed ratings are saved
 
 	DiscordToken   string `envconfig:"DISCORD_TOKEN"   required:"true"`
 	DiscordGuildID string `envconfig:"DISCORD_GUILD_ID" required:"true"`
+	// comma-separated role IDs; used until permissions.json exists in the Gist
+	DiscordDJRoles    []string `envconfig:"DISCORD_DJ_ROLES"`
+	DiscordAdminRoles []string `envconfig:"DISCORD_ADMIN_ROLES"`
+
+	NowPlayingWebhookURL string        `envconfig:"NOW_PLAYING_WEBHOOK_URL"`           // posts each song change; unused when the panel is on
+	NowPlayingChannelID  string        `envconfig:"NOW_PLAYING_CHANNEL_ID"`            // channel for the live now-playing panel
+	NowPlayingRefresh    time.Duration `envconfig:"NOW_PLAYING_REFRESH" default:"15s"` // how often the panel's progress is redrawn
+
+	VoteSkipPercent float64 `envconfig:"VOTE_SKIP_PERCENT" default:"50"`   // share of listeners needed to skip
+	VoteSkipMin     int     `envconfig:"VOTE_SKIP_MIN"     default:"1"`    // never fewer votes than this
+	VoteSkipInGame  bool    `envconfig:"VOTE_SKIP_INGAME"  default:"true"` // accept votes over the WebSocket
 
-	NowPlayingWebhookURL string `envconfig:"NOW_PLAYING_WEBHOOK_URL"`
+	PauseAutoResume time.Duration `envconfig:"PAUSE_AUTO_RESUME" default:"0"` // /pause without a duration resumes after this; 0 waits for /resume
+	InterruptDir    string        `envconfig:"INTERRUPT_DIR"`                 // folder of DFPWM clips for /interrupt
+	ControlToken    string        `envconfig:"CONTROL_TOKEN"`                 // bearer token for /control/* HTTP endpoints; unset disables them
+
+	TTSCommand  string `envconfig:"TTS_COMMAND"`          // e.g. "espeak-ng --stdout {text}"; WAV on stdout
+	TTSURL      string `envconfig:"TTS_URL"`              // or a service answering POST {"text"} with WAV
+	DJEvery     int    `envconfig:"DJ_EVERY" default:"3"` // voice a link every N song changes; 0 only /announce
+	DJTemplates string `envconfig:"DJ_TEMPLATES"`         // "|"-separated text/templates over dj.Link
+
+	LogLevel      string        `envconfig:"LOG_LEVEL"  default:"info"`         // debug, info, warn, error
+	LogFormat     string        `envconfig:"LOG_FORMAT" default:"text"`         // text or json
+	LogRepeatWait time.Duration `envconfig:"LOG_REPEAT_INTERVAL" default:"30s"` // min gap between identical tick-level errors
 }
 
 func Load() (*Config, error) {
@@ -30,5 +75,18 @@ func Load() (*Config, error) {
 	if err := envconfig.Process("", &cfg); err != nil {
 		return nil, err
 	}
+	// these drive tickers, which panic on a non-positive interval
+	for _, d := range []struct {
+		name string
+		val  time.Duration
+	}{
+		{"CHUNK_INTERVAL", cfg.ChunkInterval},
+		{"NOW_PLAYING_REFRESH", cfg.NowPlayingRefresh},
+		{"RATINGS_SAVE_INTERVAL", cfg.RatingsSaveInterval},
+	} {
+		if d.val <= 0 {
+			return nil, fmt.Errorf("%s must be positive, got %s", d.name, d.val)
+		}
+	}
 	return &cfg, nil
 }
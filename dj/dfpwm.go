@@ -0,0 +1,65 @@
+package dj
+
+// SampleRate is the rate DFPWM clips are encoded at, matching CC: Tweaked speakers.
+const SampleRate = 48000
+
+// dfpwmPrec is the fixed-point precision of the DFPWM1a response filter.
+const dfpwmPrec = 10
+
+// EncodeDFPWM packs signed 8-bit samples at SampleRate into DFPWM1a, eight
+// samples per byte, least significant bit first. It is the same converter
+// CC: Tweaked ships, so clips decode cleanly on in-game speakers.
+func EncodeDFPWM(samples []int8) []byte {
+	out := make([]byte, (len(samples)+7)/8)
+	var (
+		charge, strength int
+		previousBit      bool
+	)
+	for n := range out {
+		var b byte
+		for bit := 0; bit < 8; bit++ {
+			level := 0
+			if i := n*8 + bit; i < len(samples) {
+				level = int(samples[i])
+			}
+			currentBit := level > charge || (level == charge && charge == 127)
+			target := -128
+			if currentBit {
+				target = 127
+			}
+
+			nextCharge := charge + ((strength*(target-charge) + (1 << (dfpwmPrec - 1))) >> dfpwmPrec)
+			if nextCharge == charge && nextCharge != target {
+				if currentBit {
+					nextCharge++
+				} else {
+					nextCharge--
+				}
+			}
+
+			z := 0
+			if currentBit == previousBit {
+				z = (1 << dfpwmPrec) - 1
+			}
+			nextStrength := strength
+			if strength != z {
+				if currentBit == previousBit {
+					nextStrength++
+				} else {
+					nextStrength--
+				}
+			}
+			if nextStrength < 2<<(dfpwmPrec-8) {
+				nextStrength = 2 << (dfpwmPrec - 8)
+			}
+
+			charge, strength, previousBit = nextCharge, nextStrength, currentBit
+			b >>= 1
+			if currentBit {
+				b |= 0x80
+			}
+		}
+		out[n] = b
+	}
+	return out
+}
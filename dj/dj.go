@@ -0,0 +1,253 @@
+// Package dj voices short links between songs ("That was X by Y, up next Z")
+// with a text-to-speech backend and plays them through the broadcaster.
+package dj
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"math/rand"
+	"net/http"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/Coop25/CC-Radio/accessor"
+	"github.com/Coop25/CC-Radio/config"
+	"github.com/Coop25/CC-Radio/logging"
+	"github.com/Coop25/CC-Radio/manager"
+)
+
+const (
+	// speakTimeout bounds one synthesis, command or HTTP.
+	speakTimeout = 30 * time.Second
+	// nextWait is how long to wait after a song change for the broadcaster to plan the following track.
+	nextWait = 10 * time.Second
+)
+
+// defaultTemplates are used when DJ_TEMPLATES is empty; one is picked at random per link.
+var defaultTemplates = []string{
+	"That was {{.Prev.Name}} by {{.Prev.Artist}}. Up next, {{.Next.Name}}.",
+	"You're tuned in to CC Radio. Coming up, {{.Next.Name}} by {{.Next.Artist}}.",
+	"It's {{.Time}}. Here's {{.Next.Name}}.",
+}
+
+// Link is what announcement templates are executed with.
+type Link struct {
+	Prev      accessor.Song // the song that just finished
+	Next      accessor.Song // the song about to start
+	Listeners int
+	Time      string // e.g. "3:04 PM" in TIMEZONE
+}
+
+// Announcer renders a link ahead of each due transition and plays it the moment
+// the broadcaster switches to the song it introduces.
+type Announcer struct {
+	tts       TTS
+	b         *manager.Broadcaster
+	pl        *accessor.Playlist
+	log       *slog.Logger
+	every     int
+	templates []*template.Template
+	loc       *time.Location
+	rng       *rand.Rand
+	changes   chan accessor.Song
+}
+
+// prepared is an encoded link waiting for the transition into next.
+type prepared struct {
+	next  string
+	text  string
+	audio []byte
+}
+
+// New builds an announcer from TTS_COMMAND or TTS_URL. It returns nil without
+// an error when neither is set, which leaves the DJ switched off.
+func New(cfg *config.Config, b *manager.Broadcaster, pl *accessor.Playlist, log *slog.Logger) (*Announcer, error) {
+	var tts TTS
+	switch {
+	case cfg.TTSCommand != "":
+		c, err := NewCommandTTS(cfg.TTSCommand)
+		if err != nil {
+			return nil, err
+		}
+		tts = c
+	case cfg.TTSURL != "":
+		tts = &HTTPTTS{URL: cfg.TTSURL, Client: &http.Client{Timeout: speakTimeout}}
+	default:
+		return nil, nil
+	}
+
+	srcs := defaultTemplates
+	if strings.TrimSpace(cfg.DJTemplates) != "" {
+		srcs = strings.Split(cfg.DJTemplates, "|")
+	}
+	var templates []*template.Template
+	for n, src := range srcs {
+		t, err := template.New(fmt.Sprintf("dj%d", n)).Option("missingkey=error").Parse(strings.TrimSpace(src))
+		if err != nil {
+			return nil, fmt.Errorf("invalid DJ_TEMPLATES entry %d: %w", n, err)
+		}
+		templates = append(templates, t)
+	}
+
+	loc, err := time.LoadLocation(cfg.Timezone)
+	if err != nil {
+		return nil, fmt.Errorf("invalid TIMEZONE %q: %w", cfg.Timezone, err)
+	}
+	return &Announcer{
+		tts:       tts,
+		b:         b,
+		pl:        pl,
+		log:       logging.Component(log, "dj"),
+		every:     cfg.DJEvery,
+		templates: templates,
+		loc:       loc,
+		rng:       rand.New(rand.NewSource(time.Now().UnixNano())),
+		changes:   make(chan accessor.Song, 4),
+	}, nil
+}
+
+// Run voices a link every DJ_EVERY transitions until ctx ends; DJ_EVERY 0 leaves only Say.
+func (a *Announcer) Run(ctx context.Context) {
+	if a.every <= 0 {
+		a.log.Info("automatic links disabled (DJ_EVERY=0)")
+		return
+	}
+	a.b.OnSongChange(func(s accessor.Song) {
+		select {
+		case a.changes <- s:
+		default:
+		}
+	})
+
+	var pending *prepared
+	since := 0
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case song := <-a.changes:
+			if pending != nil {
+				a.play(pending, song)
+				since = 0
+				pending = nil
+			} else {
+				since++
+			}
+			if since+1 < a.every {
+				continue
+			}
+			pending = a.prepare(ctx, song)
+		}
+	}
+}
+
+// play inserts p if the broadcaster really moved to the song it introduces;
+// a replan or skip in the meantime makes it stale.
+func (a *Announcer) play(p *prepared, song accessor.Song) {
+	if p.next != song.ID {
+		announcements.Inc("stale")
+		a.log.Debug("dropping stale link", "expected", p.next, "got", song.ID)
+		return
+	}
+	if err := a.b.InterruptAudio("DJ", p.audio); err != nil {
+		announcements.Inc("failed")
+		a.log.Warn("link not played", "err", err)
+		return
+	}
+	announcements.Inc("played")
+	a.log.Info("link played", "text", p.text)
+}
+
+// prepare renders and encodes the link out of current into whatever is planned next.
+func (a *Announcer) prepare(ctx context.Context, current accessor.Song) *prepared {
+	next, ok := a.waitNext(ctx, current)
+	if !ok || a.isSegment(current.ID) || a.isSegment(next.ID) {
+		return nil
+	}
+	text, err := a.render(Link{
+		Prev:      current,
+		Next:      next,
+		Listeners: a.b.ListenerCount(),
+		Time:      time.Now().In(a.loc).Format("3:04 PM"),
+	})
+	if err != nil {
+		announcements.Inc("failed")
+		a.log.Warn("template failed", "err", err)
+		return nil
+	}
+	audio, err := a.synth(ctx, text)
+	if err != nil {
+		announcements.Inc("failed")
+		a.log.Warn("speech failed", "err", err)
+		return nil
+	}
+	return &prepared{next: next.ID, text: text, audio: audio}
+}
+
+// Say speaks text over the current track straight away.
+func (a *Announcer) Say(ctx context.Context, text string) error {
+	audio, err := a.synth(ctx, text)
+	if err != nil {
+		return err
+	}
+	return a.b.InterruptAudio("DJ", audio)
+}
+
+// synth runs the TTS backend and encodes its WAV output as DFPWM.
+func (a *Announcer) synth(ctx context.Context, text string) ([]byte, error) {
+	start := time.Now()
+	ctx, cancel := context.WithTimeout(ctx, speakTimeout)
+	defer cancel()
+	wav, err := a.tts.Speak(ctx, text)
+	if err != nil {
+		return nil, err
+	}
+	samples, err := DecodeWAV(wav)
+	if err != nil {
+		return nil, err
+	}
+	audio := EncodeDFPWM(samples)
+	ttsDuration.Observe(time.Since(start).Seconds())
+	return audio, nil
+}
+
+func (a *Announcer) render(l Link) (string, error) {
+	var buf bytes.Buffer
+	t := a.templates[a.rng.Intn(len(a.templates))]
+	if err := t.Execute(&buf, l); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(buf.String()), nil
+}
+
+// waitNext polls until the broadcaster has planned the track after current.
+func (a *Announcer) waitNext(ctx context.Context, current accessor.Song) (accessor.Song, bool) {
+	deadline := time.Now().Add(nextWait)
+	for time.Now().Before(deadline) {
+		np := a.b.NowPlaying()
+		if np.Song.ID != current.ID {
+			return accessor.Song{}, false // already moved on
+		}
+		if np.Next.ID != "" {
+			return np.Next, true
+		}
+		select {
+		case <-ctx.Done():
+			return accessor.Song{}, false
+		case <-time.After(200 * time.Millisecond):
+		}
+	}
+	return accessor.Song{}, false
+}
+
+func (a *Announcer) isSegment(id string) bool {
+	for _, s := range a.pl.RadioSegments() {
+		if s.ID == id {
+			return true
+		}
+	}
+	return false
+}
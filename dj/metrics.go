@@ -0,0 +1,10 @@
+package dj
+
+import "github.com/Coop25/CC-Radio/metrics"
+
+var (
+	announcements = metrics.NewCounterVec("ccradio_dj_announcements_total",
+		"DJ announcements, by result (played, stale, failed).", "result")
+	ttsDuration = metrics.NewHistogram("ccradio_tts_duration_seconds",
+		"Time to synthesise and encode one announcement.", metrics.DefBuckets)
+)
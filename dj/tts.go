@@ -0,0 +1,81 @@
+package dj
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os/exec"
+	"strings"
+)
+
+// TTS turns text into speech as a WAV file.
+type TTS interface {
+	Speak(ctx context.Context, text string) ([]byte, error)
+}
+
+// CommandTTS runs a local synthesiser such as espeak-ng that writes WAV to
+// stdout. A "{text}" argument is replaced by the text; without one the text
+// is written to stdin. No shell is involved, so the text can't inject commands.
+type CommandTTS struct {
+	Name string
+	Args []string
+}
+
+// NewCommandTTS splits a TTS_COMMAND like "espeak-ng --stdout -v en {text}".
+func NewCommandTTS(command string) (*CommandTTS, error) {
+	fields := strings.Fields(command)
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("empty TTS command")
+	}
+	return &CommandTTS{Name: fields[0], Args: fields[1:]}, nil
+}
+
+func (c *CommandTTS) Speak(ctx context.Context, text string) ([]byte, error) {
+	args := make([]string, len(c.Args))
+	useStdin := true
+	for n, a := range c.Args {
+		if a == "{text}" {
+			a, useStdin = text, false
+		}
+		args[n] = a
+	}
+	cmd := exec.CommandContext(ctx, c.Name, args...)
+	if useStdin {
+		cmd.Stdin = strings.NewReader(text)
+	}
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w: %s", c.Name, err, strings.TrimSpace(stderr.String()))
+	}
+	return out, nil
+}
+
+// HTTPTTS posts {"text": "..."} to a speech service that answers with a WAV body.
+type HTTPTTS struct {
+	URL    string
+	Client *http.Client
+}
+
+func (h *HTTPTTS) Speak(ctx context.Context, text string) ([]byte, error) {
+	body, _ := json.Marshal(map[string]string{"text": text})
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := h.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("TTS service returned %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return io.ReadAll(resp.Body)
+}
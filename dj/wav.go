@@ -0,0 +1,88 @@
+package dj
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// DecodeWAV reads an 8- or 16-bit PCM WAV file, mixes it down to mono and
+// resamples it to SampleRate as signed 8-bit samples ready for EncodeDFPWM.
+func DecodeWAV(data []byte) ([]int8, error) {
+	if len(data) < 12 || string(data[0:4]) != "RIFF" || string(data[8:12]) != "WAVE" {
+		return nil, fmt.Errorf("not a WAV file")
+	}
+	var (
+		channels, bits int
+		rate           int
+		pcm            []byte
+		haveFmt        bool
+	)
+	for off := 12; off+8 <= len(data); {
+		id := string(data[off : off+4])
+		size := int(binary.LittleEndian.Uint32(data[off+4 : off+8]))
+		body := data[off+8:]
+		if size < len(body) {
+			body = body[:size]
+		}
+		switch id {
+		case "fmt ":
+			if len(body) < 16 {
+				return nil, fmt.Errorf("short fmt chunk")
+			}
+			if format := binary.LittleEndian.Uint16(body[0:2]); format != 1 {
+				return nil, fmt.Errorf("unsupported WAV format %d (want PCM)", format)
+			}
+			channels = int(binary.LittleEndian.Uint16(body[2:4]))
+			rate = int(binary.LittleEndian.Uint32(body[4:8]))
+			bits = int(binary.LittleEndian.Uint16(body[14:16]))
+			haveFmt = true
+		case "data":
+			pcm = body
+		}
+		off += 8 + size + size%2 // chunks are word-aligned
+	}
+	if !haveFmt || pcm == nil {
+		return nil, fmt.Errorf("WAV file has no fmt or data chunk")
+	}
+	if channels < 1 || rate <= 0 || (bits != 8 && bits != 16) {
+		return nil, fmt.Errorf("unsupported WAV layout: %d channels, %d Hz, %d-bit", channels, rate, bits)
+	}
+
+	// mix down to mono in the -32768..32767 range
+	frame := channels * bits / 8
+	mono := make([]int, len(pcm)/frame)
+	for n := range mono {
+		sum := 0
+		for c := 0; c < channels; c++ {
+			at := n*frame + c*bits/8
+			if bits == 16 {
+				sum += int(int16(binary.LittleEndian.Uint16(pcm[at:])))
+			} else {
+				sum += (int(pcm[at]) - 128) << 8
+			}
+		}
+		mono[n] = sum / channels
+	}
+	return resample(mono, rate), nil
+}
+
+// resample converts mono samples at rate to SampleRate by linear
+// interpolation and scales them down to 8 bits.
+func resample(in []int, rate int) []int8 {
+	if len(in) == 0 {
+		return nil
+	}
+	n := int(int64(len(in)) * SampleRate / int64(rate))
+	out := make([]int8, n)
+	step := float64(rate) / SampleRate
+	for i := range out {
+		pos := float64(i) * step
+		j := int(pos)
+		v := float64(in[j])
+		if j+1 < len(in) {
+			v += (float64(in[j+1]) - v) * (pos - float64(j))
+		}
+		out[i] = int8(int(v) >> 8)
+	}
+	return out
+}
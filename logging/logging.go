@@ -0,0 +1,103 @@
+// Package logging builds the station's slog.Logger from config and provides
+// a limiter for errors that would otherwise repeat every tick.
+package logging
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Coop25/CC-Radio/config"
+)
+
+// New returns a logger writing to w at cfg.LogLevel, as JSON or text per cfg.LogFormat.
+func New(cfg *config.Config, w io.Writer) (*slog.Logger, error) {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(cfg.LogLevel)); err != nil {
+		return nil, fmt.Errorf("invalid LOG_LEVEL %q: %w", cfg.LogLevel, err)
+	}
+	opts := &slog.HandlerOptions{Level: level}
+
+	switch strings.ToLower(cfg.LogFormat) {
+	case "json":
+		return slog.New(slog.NewJSONHandler(w, opts)), nil
+	case "text", "":
+		return slog.New(slog.NewTextHandler(w, opts)), nil
+	default:
+		return nil, fmt.Errorf("invalid LOG_FORMAT %q (want text or json)", cfg.LogFormat)
+	}
+}
+
+// Component tags a logger with the subsystem it belongs to.
+func Component(log *slog.Logger, name string) *slog.Logger {
+	return log.With("component", name)
+}
+
+// Limiter lets one message per key through every interval, counting the
+// ones it swallowed so the next emitted line can report them.
+type Limiter struct {
+	every time.Duration
+
+	mu         sync.Mutex
+	last       map[string]time.Time
+	suppressed map[string]int
+}
+
+func NewLimiter(every time.Duration) *Limiter {
+	return &Limiter{
+		every:      every,
+		last:       make(map[string]time.Time),
+		suppressed: make(map[string]int),
+	}
+}
+
+// Allow reports whether a message for key may be logged now, and how many
+// were suppressed since the last one that was.
+func (l *Limiter) Allow(key string) (bool, int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := time.Now()
+	if t, ok := l.last[key]; ok && now.Sub(t) < l.every {
+		l.suppressed[key]++
+		return false, 0
+	}
+	n := l.suppressed[key]
+	l.last[key] = now
+	delete(l.suppressed, key)
+	return true, n
+}
+
+// Forget drops state for key, e.g. once the connection it tracked is gone.
+func (l *Limiter) Forget(key string) {
+	l.mu.Lock()
+	delete(l.last, key)
+	delete(l.suppressed, key)
+	l.mu.Unlock()
+}
+
+// Error logs at error level unless key was logged within the interval.
+func (l *Limiter) Error(log *slog.Logger, key, msg string, args ...any) {
+	ok, n := l.Allow(key)
+	if !ok {
+		return
+	}
+	if n > 0 {
+		args = append(args, "suppressed", n)
+	}
+	log.Error(msg, args...)
+}
+
+// Warn is Error at warn level.
+func (l *Limiter) Warn(log *slog.Logger, key, msg string, args ...any) {
+	ok, n := l.Allow(key)
+	if !ok {
+		return
+	}
+	if n > 0 {
+		args = append(args, "suppressed", n)
+	}
+	log.Warn(msg, args...)
+}
@@ -3,12 +3,15 @@ package main
 import (
 	"context"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
+	"os"
 
 	"github.com/Coop25/CC-Radio/accessor"
 	"github.com/Coop25/CC-Radio/client"
 	"github.com/Coop25/CC-Radio/config"
+	"github.com/Coop25/CC-Radio/dj"
+	"github.com/Coop25/CC-Radio/logging"
 	"github.com/Coop25/CC-Radio/manager"
 )
 
@@ -16,32 +19,84 @@ func main() {
 	// 1) load config
 	cfg, err := config.Load()
 	if err != nil {
-		log.Fatal(err)
+		fatal(slog.Default(), "load config failed", err)
 	}
+	log, err := logging.New(cfg, os.Stderr)
+	if err != nil {
+		fatal(slog.Default(), "init logger failed", err)
+	}
+	slog.SetDefault(log)
 
 	// 2) init playlist
 	pl := accessor.NewPlaylist(cfg)
-	fetcher := accessor.NewHTTPFetcher(cfg, pl)
-	gist := accessor.NewGistAccessor(cfg)
+	if cfg.RadioRules != "" {
+		rules, err := accessor.ParseSegmentRules(cfg.RadioRules)
+		if err != nil {
+			fatal(log, "load radio rules failed", err)
+		}
+		pl.SetSegmentRules(rules)
+	}
+	fetcher, err := accessor.NewHTTPFetcher(cfg, log)
+	if err != nil {
+		fatal(log, "converter config failed", err)
+	}
+	go fetcher.CheckHealth(context.Background(), cfg.FetchHealthInterval)
+	gist := accessor.NewGistAccessor(cfg, log)
 
 	// load existing state from Gist
 	if err := gist.LoadByID(pl); err != nil {
-		log.Fatalf("load from Gist failed: %v", err)
+		fatal(log, "load from Gist failed", err)
 	}
-	log.Println("✅ loaded playlist from Gist")
+	log.Info("loaded playlist from Gist")
+
+	perms := accessor.NewPermissions(cfg)
+	if err := gist.LoadPermissions(perms); err != nil {
+		fatal(log, "load permissions from Gist failed", err)
+	}
+	ratings := accessor.NewRatings()
+	if err := gist.LoadRatings(ratings); err != nil {
+		fatal(log, "load ratings from Gist failed", err)
+	}
+	pl.SetScorer(ratings.PlayScore)
+	sched, err := accessor.NewSchedule(cfg.Timezone, cfg.Schedule)
+	if err != nil {
+		fatal(log, "load schedule failed", err)
+	}
+	if err := gist.LoadSchedule(sched); err != nil {
+		fatal(log, "load schedule from Gist failed", err)
+	}
+	pl.SetSchedule(sched)
+	go gist.AutoSaveRatings(context.Background(), ratings, cfg.RatingsSaveInterval)
 
 	// 3) init broadcaster & HTTP
-	b := manager.NewBroadcaster(cfg, pl, fetcher)
+	b := manager.NewBroadcaster(cfg, pl, fetcher, log)
 	b.Start(context.Background())
 
-	client.RegisterWS(b)
+	ann, err := dj.New(cfg, b, pl, log)
+	if err != nil {
+		fatal(log, "DJ init failed", err)
+	}
+	if ann != nil {
+		go ann.Run(context.Background())
+	}
+
+	client.RegisterWS(cfg, b, ratings, log)
+	client.RegisterMetrics()
+	client.RegisterControl(cfg, b, pl, log)
 	// 6) Instantiate Discord bot just like everything else
-	dg, err := client.NewDiscordBot(cfg, b, fetcher, gist, pl)
+	dg, err := client.NewDiscordBot(cfg, b, fetcher, fetcher, gist, pl, perms, ratings, sched, ann, log)
 	if err != nil {
-		log.Fatalf("Discord bot init failed: %v", err)
+		fatal(log, "Discord bot init failed", err)
 	}
 	defer dg.Close()
+	client.RegisterHealth(b, gist, fetcher, dg)
+
+	log.Info("listening", "port", cfg.HTTPPort)
+	err = http.ListenAndServe(fmt.Sprintf(":%d", cfg.HTTPPort), nil)
+	fatal(log, "HTTP server stopped", err)
+}
 
-	log.Printf("listening on :%d", cfg.HTTPPort)
-	log.Fatal(http.ListenAndServe(fmt.Sprintf(":%d", cfg.HTTPPort), nil))
+func fatal(log *slog.Logger, msg string, err error) {
+	log.Error(msg, "err", err)
+	os.Exit(1)
 }
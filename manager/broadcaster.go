@@ -5,14 +5,15 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"log"
+	"io"
+	"log/slog"
 	"net/http"
 	"sync"
 	"time"
 
 	"github.com/Coop25/CC-Radio/accessor"
-	"github.com/Coop25/CC-Radio/chunker"
 	"github.com/Coop25/CC-Radio/config"
+	"github.com/Coop25/CC-Radio/logging"
 	"github.com/gorilla/websocket"
 )
 
@@ -25,28 +26,117 @@ type songChangeMsg struct {
 }
 
 type Broadcaster struct {
-	conns       map[*websocket.Conn]struct{}
+	conns       map[*websocket.Conn]*listener
 	mu          sync.Mutex
 	interval    time.Duration
 	skipCh      chan struct{}
 	playlist    *accessor.Playlist
 	cancel      context.CancelFunc
-	fetcher     accessor.Fetcher
+	audio       accessor.AudioSource
+	buffer      time.Duration // audio read ahead per track
+	retryBase   time.Duration // prefetch backoff while the converter is down
+	retryMax    time.Duration
 	webhook     string
 	http        *http.Client
 	currentSong accessor.Song // ← track what’s playing
+	log         *slog.Logger
+	errLimit    *logging.Limiter // throttles per-tick errors
+
+	// playback position of currentSong, guarded by mu
+	chunkIdx   int
+	chunkCount int
+	songHooks  []func(accessor.Song)
+
+	// clips played over the current track; clip is the one on air, guarded by mu
+	interruptCh chan Clip
+	clip        *Clip
+
+	seekCh    chan seekReq
+	playNowCh chan playNowReq
+	recent    []accessor.Song // finished tracks, oldest first, guarded by mu
+
+	// pause state, guarded by mu; resumeAt is zero when only /resume ends it
+	paused     bool
+	resumeAt   time.Time
+	lastPing   time.Time
+	autoResume time.Duration
+
+	// readiness state, guarded by mu
+	started   bool
+	lastTick  time.Time
+	nextSong  accessor.Song
+	nextReady bool
+	nextSince time.Time // fetch start while !nextReady
+
+	// skip votes for the current song, guarded by mu
+	votes       map[string]struct{}
+	votePercent float64
+	voteMin     int
+	voteInGame  bool
+
+	plays *playHistory
+
+	// listener history, guarded by mu
+	peakListeners int
+	peakAt        time.Time
+	intervalPeak  int
+	history       []ListenerSample
 }
 
 // NewBroadcaster starts the ticker loop; you can call Start(ctx) to begin.
-func NewBroadcaster(cfg *config.Config, pl *accessor.Playlist, f accessor.Fetcher) *Broadcaster {
+func NewBroadcaster(cfg *config.Config, pl *accessor.Playlist, audio accessor.AudioSource, log *slog.Logger) *Broadcaster {
+	log = logging.Component(log, "broadcaster")
+	webhook := cfg.NowPlayingWebhookURL
+	if webhook != "" && cfg.NowPlayingChannelID != "" {
+		log.Info("now-playing panel configured; not posting song changes to the webhook")
+		webhook = ""
+	}
 	return &Broadcaster{
-		conns:    make(map[*websocket.Conn]struct{}),
+		conns:    make(map[*websocket.Conn]*listener),
 		interval: cfg.ChunkInterval,
 		skipCh:   make(chan struct{}, 1),
 		playlist: pl,
-		fetcher:  f,
-		webhook:  cfg.NowPlayingWebhookURL,
+		audio:    audio,
+		buffer:   cfg.StreamBuffer,
+		webhook:  webhook,
 		http:     &http.Client{Timeout: 5 * time.Second},
+		log:      log,
+		errLimit: logging.NewLimiter(cfg.LogRepeatWait),
+
+		interruptCh: make(chan Clip, maxQueuedClips),
+		seekCh:      make(chan seekReq),
+		playNowCh:   make(chan playNowReq, 1),
+		autoResume:  cfg.PauseAutoResume,
+		retryBase:   cfg.FetchBackoff,
+		retryMax:    cfg.FetchBackoffMax,
+
+		votes:       make(map[string]struct{}),
+		votePercent: cfg.VoteSkipPercent,
+		voteMin:     cfg.VoteSkipMin,
+		voteInGame:  cfg.VoteSkipInGame,
+
+		plays: newPlayHistory(cfg.HistoryFile, log),
+	}
+}
+
+// changeSong makes song current, clears per-song state and tells everyone.
+// skipped says whether the outgoing song was cut short.
+func (b *Broadcaster) changeSong(song accessor.Song, chunks int, skipped bool) {
+	b.mu.Lock()
+	listeners := len(b.conns)
+	b.currentSong = song
+	b.chunkIdx = 0
+	b.chunkCount = chunks
+	b.votes = make(map[string]struct{})
+	hooks := b.songHooks // append-only, so safe to range after unlock
+	b.mu.Unlock()
+
+	b.plays.start(song, listeners, skipped)
+
+	b.notifySongChange(song)
+	b.announce(song)
+	for _, fn := range hooks {
+		go fn(song)
 	}
 }
 
@@ -59,15 +149,16 @@ func (b *Broadcaster) notifySongChange(song accessor.Song) {
 		Artist:   song.Artist,
 		Duration: song.Duration,
 	}
-	payload, _ := json.Marshal(msg)
+	b.broadcastJSON(msg)
+}
+
+// broadcastJSON sends v as a JSON text frame to all clients.
+func (b *Broadcaster) broadcastJSON(v interface{}) {
+	payload, _ := json.Marshal(v)
 
 	b.mu.Lock()
 	defer b.mu.Unlock()
-	for conn := range b.conns {
-		if err := conn.WriteMessage(websocket.TextMessage, payload); err != nil {
-			log.Printf("[Broadcaster] songChange WriteMessage error: %v", err)
-		}
-	}
+	b.writeAll(websocket.TextMessage, payload)
 }
 
 func (b *Broadcaster) announce(song accessor.Song) {
@@ -81,179 +172,300 @@ func (b *Broadcaster) announce(song accessor.Song) {
 
 	req, err := http.NewRequest("POST", b.webhook, bytes.NewReader(body))
 	if err != nil {
-		log.Printf("[Webhook] NewRequest error: %v", err)
+		b.log.Error("webhook request build failed", "err", err)
 		return
 	}
 	req.Header.Set("Content-Type", "application/json")
 
 	resp, err := b.http.Do(req)
 	if err != nil {
-		log.Printf("[Webhook] POST error: %v", err)
+		b.errLimit.Error(b.log, "webhook", "webhook POST failed", "err", err)
 		return
 	}
 	defer resp.Body.Close()
 	if resp.StatusCode >= 300 {
-		log.Printf("[Webhook] non-2xx status: %s", resp.Status)
+		b.errLimit.Error(b.log, "webhook", "webhook returned non-2xx", "status", resp.Status)
 	}
 }
 
-// prefetchSlices keeps retrying FetchBytes(song.ID) until it succeeds,
-// then sends the prepared chunks on out.
-func (b *Broadcaster) prefetchSlices(song accessor.Song, out chan<- [][]byte) {
+// prefetch primes song's stream in the background and hands it over on out,
+// or nil if song can't be fetched. out is unbuffered so an abandoned
+// prefetch closes its own stream.
+func (b *Broadcaster) prefetch(ctx context.Context, song accessor.Song, out chan<- *track) {
 	go func() {
-		for {
-			data, err := b.fetcher.FetchBytes(song.ID)
-			if err != nil {
-				log.Printf("[Broadcaster] Prefetch %s error: %v; retrying in 5s", song.ID, err)
-				time.Sleep(5 * time.Second)
-				continue
-			}
-			chunks := chunker.PrepareChunks(data, song.Duration, b.interval)
-			out <- chunks
+		t, err := b.primeTrack(ctx, song)
+		if ctx.Err() != nil {
+			t.close()
 			return
 		}
+		if err != nil {
+			b.log.Warn("can't fetch next track; skipping it", "song", song.ID, "err", err)
+		}
+		select {
+		case out <- t:
+		case <-ctx.Done():
+			t.close()
+		}
 	}()
 }
 
+// play sends t's next chunk to everyone and reports whether t is over. A
+// chunk that hasn't arrived yet is an underrun: nothing is sent and t holds
+// its place until the next tick.
+func (b *Broadcaster) play(t *track) bool {
+	chunk, err := t.next()
+	if chunk != nil {
+		b.mu.Lock()
+		b.writeAll(websocket.BinaryMessage, chunk)
+		b.mu.Unlock()
+		chunksSent.Inc()
+	}
+	switch {
+	case err == io.EOF:
+		return true
+	case err != nil:
+		b.log.Warn("audio stream failed; moving on", "song", t.song.ID, "chunk", t.pos, "err", err)
+		return true
+	case chunk == nil:
+		underruns.Inc()
+		return false
+	}
+	return t.finished()
+}
+
 func (b *Broadcaster) Start(ctx context.Context) {
 	ctx, cancel := context.WithCancel(ctx)
 	b.cancel = cancel
 
+	go b.sampleListeners(ctx.Done())
+
+	b.mu.Lock()
+	b.started = true
+	b.mu.Unlock()
+
 	go func() {
-		log.Printf("[Broadcaster] Starting with interval %v", b.interval)
+		b.log.Info("starting", "interval", b.interval, "buffer", b.buffer)
 		ticker := time.NewTicker(b.interval)
 		defer ticker.Stop()
 
 		var (
-			currSlices [][]byte
-			nextSlices [][]byte
-			idx        int
-			current    accessor.Song
-			next       accessor.Song
+			curr      *track // nil until something is playing
+			next      accessor.Song
+			nextTrack *track // next's stream once primed
+
+			// in-flight prefetch of next; nil once nextTrack is ready or nothing is planned
+			prefetchCh   chan *track
+			stopPrefetch = func() {}
+
+			// clip playing over curr, which holds its place until ins is nil again
+			ins *interruption
+
+			// the track rotate stopped while next's stream wasn't primed yet
+			ended        accessor.Song
+			endedSkipped bool
+			waited       bool
+
+			// after a failed prefetch nothing is planned until retryC fires
+			prefetchFailures int
+			retryC           <-chan time.Time
 		)
 
-		// Phase 0: wait for first song
-		log.Printf("[Broadcaster] Waiting for first song…")
-		for {
-			track, ok := b.playlist.Next()
+		// fetchNext makes nt the planned track and starts priming it in the
+		// background, abandoning any prefetch still running for the old plan.
+		fetchNext := func(nt accessor.Song, ok bool) {
+			stopPrefetch()
+			nextTrack.close()
+			next, nextTrack, prefetchCh, retryC = accessor.Song{}, nil, nil, nil
 			if !ok {
-				log.Printf("[Broadcaster] No song yet, blocking until NewSongCh")
-				select {
-				case <-b.playlist.NewSongCh:
-					continue
-				case <-ctx.Done():
-					return
+				b.setNext(accessor.Song{}, false)
+				return
+			}
+			next = nt
+			b.log.Debug("preloading next track", "song", next.ID)
+			var pctx context.Context
+			pctx, stopPrefetch = context.WithCancel(ctx)
+			prefetchCh = make(chan *track)
+			b.prefetch(pctx, next, prefetchCh)
+			b.setNext(next, false)
+		}
+		planNext := func() { fetchNext(b.playlist.Next()) }
+
+		// replan puts the planned track back and asks again, keeping the stream
+		// (primed or in flight) if the playlist hands back the same song.
+		replan := func() {
+			if next.ID == "" {
+				if retryC == nil {
+					planNext()
 				}
+				return
+			}
+			old := next
+			b.playlist.Requeue(old)
+			nt, ok := b.playlist.Next()
+			if ok && nt.ID == old.ID {
+				return
 			}
-			current = track
-			b.currentSong = current
-			log.Printf("[Broadcaster] Loaded initial track: ID=%s, Duration=%v", current.ID, current.Duration)
-			break
+			fetchNext(nt, ok)
+			b.log.Info("replanned next track", "was", old.ID, "now", next.ID)
 		}
-		b.notifySongChange(current)
-		b.announce(current)
 
-		// Pick and prefetch next
-		if nt, ok := b.playlist.Next(); ok {
-			next = nt
-			log.Printf("[Broadcaster] Preloading next track: ID=%s", next.ID)
+		// rotate stops curr and moves to the planned track once its stream is
+		// primed, then plans the one after. Until then the loop stays silent
+		// and the prefetch case finishes the rotation. It returns false if
+		// nothing started playing.
+		rotate := func(skipped bool) bool {
+			if curr != nil {
+				ended, endedSkipped = curr.song, skipped
+				curr.close()
+				curr = nil
+			}
+			if next.ID == "" && retryC == nil {
+				planNext()
+			}
+			if nextTrack == nil {
+				if next.ID != "" {
+					waited = true
+					b.log.Debug("waiting for next track's audio", "song", next.ID)
+				}
+				return false
+			}
+			rotations.Inc(prefetchLabel(!waited))
+			b.log.Info("rotating", "from", ended.ID, "to", next.ID, "skipped", endedSkipped)
+			b.remember(ended)
+			curr, nextTrack = nextTrack, nil
+			b.changeSong(curr.song, curr.chunks, endedSkipped)
+			ended, endedSkipped, waited = accessor.Song{}, false, false
+			planNext()
+			return true
 		}
-		currSlices = b.loadSlices(current)
 
-		prefetchCh := make(chan [][]byte, 1)
-		b.prefetchSlices(next, prefetchCh)
-		nextSlices = <-prefetchCh
-		log.Printf("[Broadcaster] Prepared %d chunks for current, %d for next", len(currSlices), len(nextSlices))
-		idx = 0
+		// nothing is playing until the first song is planned and primed
+		b.log.Info("waiting for first song")
+		planNext()
 
-		// Phase 1: main loop
+		lastTick := time.Now()
 		for {
 			select {
 			case <-ctx.Done():
-				log.Printf("[Broadcaster] Stopping")
+				stopPrefetch()
+				curr.close()
+				nextTrack.close()
+				if ins != nil {
+					ins.clip.audio.close()
+				}
+				b.log.Info("stopping")
 				return
 
-			case <-ticker.C:
-				if len(currSlices) == 0 {
+			case t := <-prefetchCh:
+				if t == nil {
+					// back off so a converter that turns every song away
+					// isn't asked for the whole playlist in a burst
+					prefetchFailures++
+					d := accessor.Backoff(prefetchFailures-1, b.retryBase, b.retryMax)
+					b.errLimit.Warn(b.log, "prefetch", "next track failed to load; trying another", "song", next.ID, "failures", prefetchFailures, "in", d)
+					stopPrefetch()
+					next, prefetchCh = accessor.Song{}, nil
+					b.setNext(accessor.Song{}, false)
+					retryC = time.After(d)
 					continue
 				}
+				prefetchFailures = 0
+				nextTrack, prefetchCh = t, nil
+				b.setNext(next, true)
+				b.log.Debug("prefetched next", "song", next.ID)
+				if curr == nil {
+					rotate(false)
+				}
+
+			case now := <-ticker.C:
+				drift := now.Sub(lastTick) - b.interval
+				if drift < 0 {
+					drift = -drift
+				}
+				tickerDrift.Observe(drift.Seconds())
+				lastTick = now
 				b.mu.Lock()
-				for conn := range b.conns {
-					if err := conn.WriteMessage(websocket.BinaryMessage, currSlices[idx]); err != nil {
-						log.Printf("[Broadcaster] WriteMessage error: %v", err)
+				b.lastTick = now
+				b.mu.Unlock()
+
+				if b.holding(now) {
+					continue
+				}
+				if ins != nil {
+					if b.play(ins.clip.audio) {
+						ins = b.endClip(ins, curr.at())
 					}
+					continue
 				}
+				if curr == nil {
+					continue
+				}
+				over := b.play(curr)
+				b.mu.Lock()
+				b.chunkIdx = curr.pos
 				b.mu.Unlock()
+				if over {
+					rotate(false)
+				}
 
-				idx++
-				if idx >= len(currSlices) {
-					// rotate
-					log.Printf("[Broadcaster] Finished %s; rotating to %s", current.ID, next.ID)
-					currSlices = nextSlices
-					current = next
-					b.currentSong = current
-
-					b.notifySongChange(current)
-					b.announce(current)
-
-					// fetch the following track in background
-					if nt, ok := b.playlist.Next(); ok {
-						next = nt
-						log.Printf("[Broadcaster] Preloading next track: ID=%s", next.ID)
-					}
-					prefetchCh = make(chan [][]byte, 1)
-					b.prefetchSlices(next, prefetchCh)
-					nextSlices = <-prefetchCh
-					log.Printf("[Broadcaster] New next: %s (%d chunks)", next.ID, len(nextSlices))
+			case <-retryC:
+				planNext()
 
-					idx = 0
+			case <-b.skipCh:
+				if ins != nil {
+					b.log.Info("skip received; ending interrupt")
+					ins = b.endClip(ins, curr.at())
+					continue
 				}
+				b.log.Info("skip received; rotating immediately")
+				rotate(true)
 
-			case <-b.skipCh:
-				log.Printf("[Broadcaster] Skip received; rotating immediately")
-				currSlices = nextSlices
-				current = next
-				b.currentSong = current
+			case req := <-b.playNowCh:
+				if req.audio == nil {
+					b.fetchPlayNow(ctx, req)
+					continue
+				}
+				// the cut track isn't remembered, so repeated /previous steps further back
+				if curr != nil {
+					b.log.Info("playing earlier track", "from", curr.song.ID, "to", req.song.ID)
+					curr.close()
+				}
+				curr = req.audio
+				ended, endedSkipped, waited = accessor.Song{}, false, false
+				b.changeSong(curr.song, curr.chunks, true)
 
-				b.notifySongChange(current)
-				b.announce(current)
+			case req := <-b.seekCh:
+				// during an interrupt this moves where the held track resumes
+				b.applySeek(req, curr)
 
-				if nt, ok := b.playlist.Next(); ok {
-					next = nt
-					log.Printf("[Broadcaster] Preloading next track: ID=%s", next.ID)
+			case c := <-b.interruptCh:
+				if ins != nil {
+					ins.queue = append(ins.queue, c)
+					continue
 				}
-				prefetchCh = make(chan [][]byte, 1)
-				b.prefetchSlices(next, prefetchCh)
-				nextSlices = <-prefetchCh
-				log.Printf("[Broadcaster] Now playing %s; next queued %s", current.ID, next.ID)
-				idx = 0
+				ins = b.beginClip(c)
+
+			case <-b.playlist.PlanChangedCh:
+				replan()
 
 			case <-b.playlist.NewSongCh:
-				if len(nextSlices) == 0 {
-					log.Printf("[Broadcaster] New song mid‐playback; loading as next")
-					if nt, ok := b.playlist.Next(); ok {
-						next = nt
-						log.Printf("[Broadcaster] Preloading next track: ID=%s", next.ID)
-					}
-					prefetchCh = make(chan [][]byte, 1)
-					b.prefetchSlices(next, prefetchCh)
-					nextSlices = <-prefetchCh
-					log.Printf("[Broadcaster] Loaded next: %s (%d chunks)", next.ID, len(nextSlices))
+				if next.ID == "" && retryC == nil {
+					b.log.Info("new song while idle; loading as next")
+					planNext()
 				} else {
-					log.Printf("[Broadcaster] Detected new song mid‐playback; will queue after current finishes")
+					b.log.Debug("new song mid-playback; will queue after current finishes")
 				}
 			}
 		}
 	}()
 }
 
-func (b *Broadcaster) loadSlices(s accessor.Song) [][]byte {
-	data, err := b.fetcher.FetchBytes(s.ID)
-	if err != nil {
-		// handle/log error and maybe return empty slice or panic, as appropriate
-		panic(err)
+// prefetchLabel reports whether the next track's audio was ready at rotation.
+func prefetchLabel(ready bool) string {
+	if ready {
+		return "true"
 	}
-	return chunker.PrepareChunks(data, s.Duration, b.interval)
+	return "false"
 }
 
 // Skip signals an immediate jump to the pre‐queued track.
@@ -273,18 +485,23 @@ func (b *Broadcaster) DeleteCurrent() error {
 	}
 	id := b.currentSong.ID
 	b.playlist.Remove(id)
-	log.Printf("[Broadcaster] Deleted current song %s from queue & randomNext", id)
+	b.log.Info("deleted current song from queue & randomNext", "song", id)
 	b.Skip()
 	return nil
 }
 
 func (b *Broadcaster) Register(conn *websocket.Conn) {
 	b.mu.Lock()
-	b.conns[conn] = struct{}{}
+	b.conns[conn] = newListener(conn)
+	b.trackPeak()
 	b.mu.Unlock()
 }
 func (b *Broadcaster) Unregister(conn *websocket.Conn) {
 	b.mu.Lock()
+	if l, ok := b.conns[conn]; ok {
+		b.errLimit.Forget("write:" + l.remoteAddr)
+	}
 	delete(b.conns, conn)
+	listenersGauge.Set(float64(len(b.conns)))
 	b.mu.Unlock()
 }
@@ -0,0 +1,55 @@
+package manager
+
+import (
+	"time"
+
+	"github.com/Coop25/CC-Radio/accessor"
+)
+
+// Status is what the broadcaster reports for readiness checks.
+type Status struct {
+	Started        bool      `json:"started"`
+	LastTick       time.Time `json:"last_tick"`
+	Ticking        bool      `json:"ticking"`
+	CurrentSong    string    `json:"current_song,omitempty"`
+	NextSong       string    `json:"next_song,omitempty"`
+	NextPrefetched bool      `json:"next_prefetched"`
+	Paused         bool      `json:"paused"`
+	// when the next track's fetch began; zero once it is ready
+	NextFetchingSince time.Time `json:"next_fetching_since,omitempty"`
+}
+
+// Status reports whether the ticker loop is alive and the next track is ready.
+// A tick older than a few intervals (or 2s, whichever is larger) counts as stalled.
+func (b *Broadcaster) Status() Status {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	stall := 5 * b.interval
+	if stall < 2*time.Second {
+		stall = 2 * time.Second
+	}
+	return Status{
+		Started:        b.started,
+		LastTick:       b.lastTick,
+		Ticking:        !b.lastTick.IsZero() && time.Since(b.lastTick) < stall,
+		CurrentSong:    b.currentSong.ID,
+		NextSong:       b.nextSong.ID,
+		NextPrefetched: b.nextReady,
+		Paused:         b.paused,
+
+		NextFetchingSince: b.nextSince,
+	}
+}
+
+// setNext records the planned track and whether its audio is ready.
+func (b *Broadcaster) setNext(song accessor.Song, ready bool) {
+	b.mu.Lock()
+	b.nextSong = song
+	b.nextReady = ready
+	b.nextSince = time.Time{}
+	if !ready && song.ID != "" {
+		b.nextSince = time.Now()
+	}
+	b.mu.Unlock()
+}
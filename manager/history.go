@@ -0,0 +1,221 @@
+package manager
+
+import (
+	"bufio"
+	"encoding/json"
+	"log/slog"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Coop25/CC-Radio/accessor"
+)
+
+// PlayRecord is one entry of the play history.
+type PlayRecord struct {
+	SongID    string    `json:"song_id"`
+	Name      string    `json:"name"`
+	Artist    string    `json:"artist"`
+	StartedAt time.Time `json:"started_at"`
+	EndedAt   time.Time `json:"ended_at,omitempty"` // zero while still playing
+	Skipped   bool      `json:"skipped"`
+	Listeners int       `json:"listeners"`
+}
+
+// TrackCount pairs a track with how often something happened to it.
+type TrackCount struct {
+	SongID string `json:"song_id"`
+	Name   string `json:"name"`
+	Artist string `json:"artist"`
+	Count  int    `json:"count"`
+}
+
+// PlayStats summarises the play history.
+type PlayStats struct {
+	Plays       int           `json:"plays"`
+	Skips       int           `json:"skips"`
+	Airtime     time.Duration `json:"airtime"`
+	Since       time.Time     `json:"since"`
+	TopPlayed   []TrackCount  `json:"top_played"`
+	MostSkipped []TrackCount  `json:"most_skipped"`
+}
+
+// historyKeep is how many recent plays are kept in memory for History; the
+// aggregates behind PlayStats cover every play ever recorded.
+const historyKeep = 500
+
+// playHistory is an append-only log of what was played, optionally mirrored
+// to a JSON-lines file so it survives restarts. Only the latest plays stay
+// in memory; the stats are totalled as each play is recorded.
+type playHistory struct {
+	mu      sync.Mutex
+	records []PlayRecord // the latest historyKeep, oldest first
+	file    *os.File
+	log     *slog.Logger
+
+	totals PlayStats // Plays, Skips, Since, and Airtime of finished plays
+	plays  map[string]*TrackCount
+	skips  map[string]*TrackCount
+}
+
+// newPlayHistory loads path (if set) and keeps it open for appending.
+func newPlayHistory(path string, log *slog.Logger) *playHistory {
+	h := &playHistory{
+		log:   log,
+		plays: make(map[string]*TrackCount),
+		skips: make(map[string]*TrackCount),
+	}
+	if path == "" {
+		return h
+	}
+
+	if f, err := os.Open(path); err == nil {
+		sc := bufio.NewScanner(f)
+		for sc.Scan() {
+			var r PlayRecord
+			if err := json.Unmarshal(sc.Bytes(), &r); err != nil {
+				log.Warn("skipping bad history line", "err", err)
+				continue
+			}
+			h.addLocked(r)
+			if !r.EndedAt.IsZero() {
+				h.endLocked(r)
+			}
+		}
+		f.Close()
+		log.Info("loaded play history", "records", h.totals.Plays)
+	}
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		log.Error("cannot open history file; history will not persist", "path", path, "err", err)
+		return h
+	}
+	h.file = f
+	return h
+}
+
+// start closes the running record (if any) and opens one for song.
+func (h *playHistory) start(song accessor.Song, listeners int, prevSkipped bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	now := time.Now()
+	h.finishLocked(now, prevSkipped)
+	h.addLocked(PlayRecord{
+		SongID:    song.ID,
+		Name:      song.Name,
+		Artist:    song.Artist,
+		StartedAt: now,
+		Listeners: listeners,
+	})
+}
+
+// addLocked appends a play, dropping the oldest kept one past historyKeep,
+// and counts it; caller holds h.mu.
+func (h *playHistory) addLocked(r PlayRecord) {
+	h.records = append(h.records, r)
+	if n := len(h.records); n > historyKeep {
+		h.records = append(h.records[:0], h.records[n-historyKeep:]...)
+	}
+	if h.totals.Since.IsZero() || r.StartedAt.Before(h.totals.Since) {
+		h.totals.Since = r.StartedAt
+	}
+	h.totals.Plays++
+	countTrack(h.plays, r)
+}
+
+// endLocked counts what is only known once a play is over; caller holds h.mu.
+func (h *playHistory) endLocked(r PlayRecord) {
+	h.totals.Airtime += r.EndedAt.Sub(r.StartedAt)
+	if r.Skipped {
+		h.totals.Skips++
+		countTrack(h.skips, r)
+	}
+}
+
+// finishLocked stamps the last record, counts it and writes it to the file;
+// caller holds h.mu.
+func (h *playHistory) finishLocked(now time.Time, skipped bool) {
+	if len(h.records) == 0 {
+		return
+	}
+	last := &h.records[len(h.records)-1]
+	if !last.EndedAt.IsZero() {
+		return
+	}
+	last.EndedAt = now
+	last.Skipped = skipped
+	h.endLocked(*last)
+	if h.file == nil {
+		return
+	}
+	line, _ := json.Marshal(last)
+	if _, err := h.file.Write(append(line, '\n')); err != nil {
+		h.log.Error("append to history file failed", "err", err)
+	}
+}
+
+// recent returns up to limit records, newest first (limit <= 0 for all kept).
+func (h *playHistory) recent(limit int) []PlayRecord {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	n := len(h.records)
+	if limit > 0 && limit < n {
+		n = limit
+	}
+	out := make([]PlayRecord, n)
+	for i := 0; i < n; i++ {
+		out[i] = h.records[len(h.records)-1-i]
+	}
+	return out
+}
+
+func (h *playHistory) stats(top int) PlayStats {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	st := h.totals
+	// the running play's airtime so far
+	if n := len(h.records); n > 0 && h.records[n-1].EndedAt.IsZero() {
+		st.Airtime += time.Since(h.records[n-1].StartedAt)
+	}
+	st.TopPlayed = topCounts(h.plays, top)
+	st.MostSkipped = topCounts(h.skips, top)
+	return st
+}
+
+func countTrack(m map[string]*TrackCount, r PlayRecord) {
+	tc, ok := m[r.SongID]
+	if !ok {
+		tc = &TrackCount{SongID: r.SongID, Name: r.Name, Artist: r.Artist}
+		m[r.SongID] = tc
+	}
+	tc.Count++
+}
+
+func topCounts(m map[string]*TrackCount, n int) []TrackCount {
+	out := make([]TrackCount, 0, len(m))
+	for _, tc := range m {
+		out = append(out, *tc)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Count != out[j].Count {
+			return out[i].Count > out[j].Count
+		}
+		return out[i].Name < out[j].Name
+	})
+	if n > 0 && len(out) > n {
+		out = out[:n]
+	}
+	return out
+}
+
+// History returns the most recent plays, newest first; the first entry may still be playing.
+func (b *Broadcaster) History(limit int) []PlayRecord {
+	return b.plays.recent(limit)
+}
+
+// PlayStats aggregates the whole play history, listing the top n tracks per category.
+func (b *Broadcaster) PlayStats(n int) PlayStats {
+	return b.plays.stats(n)
+}
@@ -0,0 +1,122 @@
+package manager
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/Coop25/CC-Radio/accessor"
+	"github.com/Coop25/CC-Radio/chunker"
+)
+
+// maxQueuedClips bounds how many interrupts can wait behind the one on air.
+const maxQueuedClips = 8
+
+// interruptPrimeTimeout bounds how long InterruptSong waits for a song's buffer to fill.
+const interruptPrimeTimeout = 30 * time.Second
+
+// Clip is audio played over the current track by an interrupt.
+type Clip struct {
+	Title  string
+	Song   accessor.Song // set when the clip is a library song or radio segment
+	length time.Duration
+	audio  *track
+}
+
+// Length is how long the clip plays for.
+func (c Clip) Length() time.Duration {
+	return c.length
+}
+
+type interruptMsg struct {
+	Type     string        `json:"type"` // "interrupt" or "resume"
+	Title    string        `json:"title,omitempty"`
+	ID       string        `json:"id,omitempty"`
+	Duration time.Duration `json:"duration,omitempty"`
+	Position time.Duration `json:"position,omitempty"` // resume: where the interrupted track picks up
+}
+
+// interruption is the loop's state while clips play over the current track.
+type interruption struct {
+	clip  Clip
+	queue []Clip
+}
+
+// InterruptSong starts streaming song and, once its buffer is primed, plays
+// it over the current track, which resumes from the same chunk afterwards.
+// It gives up if ctx ends or the buffer takes longer than interruptPrimeTimeout.
+func (b *Broadcaster) InterruptSong(ctx context.Context, song accessor.Song) error {
+	ctx, cancel := context.WithTimeout(ctx, interruptPrimeTimeout)
+	defer cancel()
+	t := b.openTrack(song, b.songOpener(song), song.Duration)
+	select {
+	case <-t.stream.Primed():
+	case <-ctx.Done():
+		t.close()
+		return fmt.Errorf("fetch %s: %w", song.ID, ctx.Err())
+	}
+	if err := t.stream.Err(); err != nil && err != io.EOF {
+		t.close()
+		return fmt.Errorf("fetch %s: %w", song.ID, err)
+	}
+	return b.interrupt(Clip{Title: song.Name, Song: song, length: song.Duration, audio: t})
+}
+
+// InterruptAudio plays raw DFPWM audio (a local file, an announcement) over the current track.
+func (b *Broadcaster) InterruptAudio(title string, dfpwm []byte) error {
+	if len(dfpwm) == 0 {
+		return fmt.Errorf("clip %q is empty", title)
+	}
+	d := time.Duration(len(dfpwm)) * time.Second / chunker.BytesPerSecond
+	return b.interrupt(Clip{Title: title, length: d, audio: b.openTrack(accessor.Song{}, bytesOpener(dfpwm), d)})
+}
+
+func (b *Broadcaster) interrupt(c Clip) error {
+	select {
+	case b.interruptCh <- c:
+		return nil
+	default:
+		c.audio.close()
+		return fmt.Errorf("too many interrupts queued; try again shortly")
+	}
+}
+
+// Interrupting returns the clip on air, if any.
+func (b *Broadcaster) Interrupting() (Clip, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.clip == nil {
+		return Clip{}, false
+	}
+	return *b.clip, true
+}
+
+// beginClip puts c on air and tells clients the current track is on hold.
+func (b *Broadcaster) beginClip(c Clip) *interruption {
+	b.mu.Lock()
+	b.clip = &c
+	b.mu.Unlock()
+	interrupts.Inc()
+	b.log.Info("interrupt on air", "title", c.Title, "length", c.Length())
+	b.broadcastJSON(interruptMsg{Type: "interrupt", Title: c.Title, ID: c.Song.ID, Duration: c.Length()})
+	return &interruption{clip: c}
+}
+
+// endClip moves on to the next queued clip, or returns nil after telling
+// clients the held track resumes at chunk resumeIdx.
+func (b *Broadcaster) endClip(ins *interruption, resumeIdx int) *interruption {
+	ins.clip.audio.close()
+	if len(ins.queue) > 0 {
+		next := b.beginClip(ins.queue[0])
+		next.queue = ins.queue[1:]
+		return next
+	}
+	b.mu.Lock()
+	b.clip = nil
+	current := b.currentSong
+	b.mu.Unlock()
+	b.log.Info("interrupt over; resuming", "song", current.ID, "chunk", resumeIdx)
+	b.broadcastJSON(interruptMsg{Type: "resume", ID: current.ID, Position: time.Duration(resumeIdx) * b.interval})
+	return nil
+}
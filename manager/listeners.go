@@ -0,0 +1,197 @@
+package manager
+
+import (
+	"sort"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// how often the listener count is sampled, and how many samples we keep (24h)
+const (
+	listenerSampleInterval = time.Minute
+	listenerHistorySize    = 24 * 60
+)
+
+var listenerSeq uint64
+
+// listener is the broadcaster's bookkeeping for one WebSocket connection.
+// All fields are guarded by Broadcaster.mu.
+type listener struct {
+	id            uint64
+	remoteAddr    string
+	connectedAt   time.Time
+	computerID    int
+	label         string
+	world         string
+	identified    bool
+	bytesSent     int64
+	droppedFrames int64
+}
+
+// ListenerInfo is a read-only snapshot of one connected listener.
+type ListenerInfo struct {
+	ID            uint64    `json:"id"`
+	RemoteAddr    string    `json:"remote_addr,omitempty"`
+	ConnectedAt   time.Time `json:"connected_at"`
+	ComputerID    int       `json:"computer_id,omitempty"`
+	Label         string    `json:"label,omitempty"`
+	World         string    `json:"world,omitempty"`
+	Identified    bool      `json:"identified"`
+	BytesSent     int64     `json:"bytes_sent"`
+	DroppedFrames int64     `json:"dropped_frames"`
+}
+
+// ListenerSample is the listener count observed over one sample interval.
+type ListenerSample struct {
+	At    time.Time `json:"at"`
+	Count int       `json:"count"`
+	Peak  int       `json:"peak"`
+}
+
+// ListenerStats summarises the current audience and its recent history.
+type ListenerStats struct {
+	Current   int              `json:"current"`
+	Peak      int              `json:"peak"`
+	PeakAt    time.Time        `json:"peak_at"`
+	Average   float64          `json:"average"`
+	Listeners []ListenerInfo   `json:"listeners"`
+	History   []ListenerSample `json:"history"`
+}
+
+func newListener(conn *websocket.Conn) *listener {
+	return &listener{
+		id:          atomic.AddUint64(&listenerSeq, 1),
+		remoteAddr:  conn.RemoteAddr().String(),
+		connectedAt: time.Now(),
+	}
+}
+
+func (l *listener) info() ListenerInfo {
+	return ListenerInfo{
+		ID:            l.id,
+		RemoteAddr:    l.remoteAddr,
+		ConnectedAt:   l.connectedAt,
+		ComputerID:    l.computerID,
+		Label:         l.label,
+		World:         l.world,
+		Identified:    l.identified,
+		BytesSent:     l.bytesSent,
+		DroppedFrames: l.droppedFrames,
+	}
+}
+
+// Identify records what a client reported about itself in its hello message.
+// Only the first hello counts: the identity keys the client's votes, so a
+// connection mustn't be able to change it and vote again.
+func (b *Broadcaster) Identify(conn *websocket.Conn, computerID int, label, world string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	l, ok := b.conns[conn]
+	if !ok || l.identified {
+		return
+	}
+	l.computerID = computerID
+	l.label = label
+	l.world = world
+	l.identified = true
+}
+
+// ListenerCount returns the number of currently connected clients.
+func (b *Broadcaster) ListenerCount() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.conns)
+}
+
+// Listeners returns a snapshot of every connected client plus peak/average history.
+func (b *Broadcaster) Listeners() ListenerStats {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	st := ListenerStats{
+		Current:   len(b.conns),
+		Peak:      b.peakListeners,
+		PeakAt:    b.peakAt,
+		Listeners: make([]ListenerInfo, 0, len(b.conns)),
+		History:   append([]ListenerSample(nil), b.history...),
+	}
+	for _, l := range b.conns {
+		st.Listeners = append(st.Listeners, l.info())
+	}
+	sort.Slice(st.Listeners, func(i, j int) bool {
+		return st.Listeners[i].ID < st.Listeners[j].ID
+	})
+
+	if len(b.history) > 0 {
+		total := 0
+		for _, s := range b.history {
+			total += s.Count
+		}
+		st.Average = float64(total) / float64(len(b.history))
+	} else {
+		st.Average = float64(st.Current)
+	}
+	return st
+}
+
+// trackPeak updates the all-time and per-interval peaks; caller holds b.mu.
+func (b *Broadcaster) trackPeak() {
+	n := len(b.conns)
+	listenersGauge.Set(float64(n))
+	if n > b.peakListeners {
+		b.peakListeners = n
+		b.peakAt = time.Now()
+	}
+	if n > b.intervalPeak {
+		b.intervalPeak = n
+	}
+}
+
+// sampleListeners appends one history sample every listenerSampleInterval.
+func (b *Broadcaster) sampleListeners(done <-chan struct{}) {
+	t := time.NewTicker(listenerSampleInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case now := <-t.C:
+			b.mu.Lock()
+			b.history = append(b.history, ListenerSample{
+				At:    now,
+				Count: len(b.conns),
+				Peak:  b.intervalPeak,
+			})
+			if len(b.history) > listenerHistorySize {
+				b.history = b.history[len(b.history)-listenerHistorySize:]
+			}
+			b.intervalPeak = len(b.conns)
+			b.mu.Unlock()
+		}
+	}
+}
+
+// writeAll sends one frame to every client, tracking bytes and drops; caller holds b.mu.
+func (b *Broadcaster) writeAll(msgType int, payload []byte) {
+	for conn, l := range b.conns {
+		if err := conn.WriteMessage(msgType, payload); err != nil {
+			l.droppedFrames++
+			writeErrors.Inc()
+			b.errLimit.Error(b.log, "write:"+l.remoteAddr, "write to listener failed", "remote", l.remoteAddr, "err", err)
+			continue
+		}
+		l.bytesSent += int64(len(payload))
+	}
+}
+
+// pingAll sends a WebSocket ping to every client; caller holds b.mu.
+func (b *Broadcaster) pingAll(now time.Time) {
+	for conn, l := range b.conns {
+		if err := conn.WriteControl(websocket.PingMessage, nil, now.Add(time.Second)); err != nil {
+			writeErrors.Inc()
+			b.errLimit.Error(b.log, "write:"+l.remoteAddr, "ping to listener failed", "remote", l.remoteAddr, "err", err)
+		}
+	}
+}
@@ -0,0 +1,21 @@
+package manager
+
+import "github.com/Coop25/CC-Radio/metrics"
+
+var (
+	listenersGauge = metrics.NewGauge("ccradio_listeners",
+		"Currently connected WebSocket clients.")
+	chunksSent = metrics.NewCounter("ccradio_chunks_sent_total",
+		"Audio chunks broadcast (one per tick, regardless of listener count).")
+	writeErrors = metrics.NewCounter("ccradio_write_errors_total",
+		"Frames that failed to write to a client.")
+	rotations = metrics.NewCounterVec("ccradio_rotations_total",
+		"Track rotations, by whether the next track was already prefetched.", "prefetched")
+	underruns = metrics.NewCounter("ccradio_underruns_total",
+		"Ticks where the current track's audio hadn't arrived yet, so nothing was sent.")
+	interrupts = metrics.NewCounter("ccradio_interrupts_total",
+		"Clips played over the current track.")
+	tickerDrift = metrics.NewHistogram("ccradio_ticker_drift_seconds",
+		"Absolute difference between the observed and configured tick interval.",
+		[]float64{0.001, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1})
+)
@@ -0,0 +1,49 @@
+package manager
+
+import (
+	"time"
+
+	"github.com/Coop25/CC-Radio/accessor"
+)
+
+// NowPlaying is a snapshot of what is on air.
+type NowPlaying struct {
+	Song      accessor.Song
+	Next      accessor.Song
+	Position  time.Duration // how far into Song the broadcast is
+	Length    time.Duration // expected length of the audio, from the song's duration
+	Listeners int
+	Interrupt string    // title of a clip playing over Song, if any
+	Paused    bool      // playback is on hold
+	ResumeAt  time.Time // when a pause ends by itself; zero if it doesn't
+}
+
+// NowPlaying returns the current track, its progress and what is up next.
+func (b *Broadcaster) NowPlaying() NowPlaying {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	length := time.Duration(b.chunkCount) * b.interval
+	if length == 0 {
+		length = b.currentSong.Duration
+	}
+	np := NowPlaying{
+		Song:      b.currentSong,
+		Next:      b.nextSong,
+		Position:  time.Duration(b.chunkIdx) * b.interval,
+		Length:    length,
+		Listeners: len(b.conns),
+		Paused:    b.paused,
+		ResumeAt:  b.resumeAt,
+	}
+	if b.clip != nil {
+		np.Interrupt = b.clip.Title
+	}
+	return np
+}
+
+// OnSongChange registers fn to be called (in its own goroutine) whenever the track changes.
+func (b *Broadcaster) OnSongChange(fn func(accessor.Song)) {
+	b.mu.Lock()
+	b.songHooks = append(b.songHooks, fn)
+	b.mu.Unlock()
+}
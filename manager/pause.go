@@ -0,0 +1,90 @@
+package manager
+
+import (
+	"fmt"
+	"time"
+)
+
+// pingInterval is how often paused listeners are pinged so proxies and
+// clients don't drop a connection that has gone quiet.
+const pingInterval = 15 * time.Second
+
+type pauseMsg struct {
+	Type     string        `json:"type"` // "paused" or "resumed"
+	ID       string        `json:"id,omitempty"`
+	Position time.Duration `json:"position"`
+	ResumeAt *time.Time    `json:"resume_at,omitempty"`
+}
+
+// Pause holds playback at the current chunk. autoResume > 0 resumes after that
+// long; 0 uses PAUSE_AUTO_RESUME, which may itself be 0 for "until /resume".
+func (b *Broadcaster) Pause(autoResume time.Duration) error {
+	b.mu.Lock()
+	if b.paused {
+		b.mu.Unlock()
+		return fmt.Errorf("already paused")
+	}
+	now := time.Now()
+	b.paused = true
+	b.lastPing = now
+	if autoResume <= 0 {
+		autoResume = b.autoResume
+	}
+	b.resumeAt = time.Time{}
+	msg := pauseMsg{Type: "paused", ID: b.currentSong.ID, Position: time.Duration(b.chunkIdx) * b.interval}
+	if autoResume > 0 {
+		resumeAt := now.Add(autoResume)
+		b.resumeAt = resumeAt
+		msg.ResumeAt = &resumeAt // a copy: the message is encoded after unlocking
+	}
+	b.mu.Unlock()
+
+	b.log.Info("paused", "song", msg.ID, "position", msg.Position, "auto_resume", autoResume)
+	b.broadcastJSON(msg)
+	return nil
+}
+
+// Resume continues playback from where Pause left it.
+func (b *Broadcaster) Resume() error {
+	b.mu.Lock()
+	if !b.paused {
+		b.mu.Unlock()
+		return fmt.Errorf("not paused")
+	}
+	b.paused = false
+	b.resumeAt = time.Time{}
+	msg := pauseMsg{Type: "resumed", ID: b.currentSong.ID, Position: time.Duration(b.chunkIdx) * b.interval}
+	b.mu.Unlock()
+
+	b.log.Info("resumed", "song", msg.ID, "position", msg.Position)
+	b.broadcastJSON(msg)
+	return nil
+}
+
+// Paused reports whether playback is on hold and when it resumes by itself (zero: never).
+func (b *Broadcaster) Paused() (bool, time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.paused, b.resumeAt
+}
+
+// holding reports whether the tick at now should send nothing. It resumes
+// once the auto-resume time passes and keeps paused connections pinged.
+func (b *Broadcaster) holding(now time.Time) bool {
+	b.mu.Lock()
+	if !b.paused {
+		b.mu.Unlock()
+		return false
+	}
+	if !b.resumeAt.IsZero() && !now.Before(b.resumeAt) {
+		b.mu.Unlock()
+		b.Resume()
+		return false
+	}
+	if now.Sub(b.lastPing) >= pingInterval {
+		b.lastPing = now
+		b.pingAll(now)
+	}
+	b.mu.Unlock()
+	return true
+}
@@ -0,0 +1,87 @@
+package manager
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/Coop25/CC-Radio/accessor"
+)
+
+// recentSize is how many finished tracks /previous can step back through.
+const recentSize = 10
+
+// playNowReq asks the loop to play song straight away, keeping the planned next
+// track. audio is nil until its stream has been primed.
+type playNowReq struct {
+	song  accessor.Song
+	audio *track
+}
+
+// Replay restarts the current track from the beginning.
+func (b *Broadcaster) Replay() error {
+	_, err := b.Seek(0)
+	return err
+}
+
+// Previous cuts the current track and plays the one before it; the planned
+// next track still follows. Repeated calls step further back.
+func (b *Broadcaster) Previous() (accessor.Song, error) {
+	b.mu.Lock()
+	if len(b.recent) == 0 {
+		b.mu.Unlock()
+		return accessor.Song{}, fmt.Errorf("no earlier track to go back to")
+	}
+	song := b.recent[len(b.recent)-1]
+	b.recent = b.recent[:len(b.recent)-1]
+	b.mu.Unlock()
+
+	select {
+	case b.playNowCh <- playNowReq{song: song}:
+		return song, nil
+	default:
+		b.remember(song)
+		return accessor.Song{}, fmt.Errorf("already going back; try again shortly")
+	}
+}
+
+// remember records a finished track for Previous.
+func (b *Broadcaster) remember(song accessor.Song) {
+	if song.ID == "" {
+		return
+	}
+	b.mu.Lock()
+	b.recent = append(b.recent, song)
+	if len(b.recent) > recentSize {
+		b.recent = b.recent[len(b.recent)-recentSize:]
+	}
+	b.mu.Unlock()
+}
+
+// fetchPlayNow primes req's stream in the background (the audio cache
+// usually has it) and hands the request back to the loop.
+func (b *Broadcaster) fetchPlayNow(ctx context.Context, req playNowReq) {
+	go func() {
+		start := time.Now()
+		t := b.openTrack(req.song, b.songOpener(req.song), req.song.Duration)
+		select {
+		case <-t.stream.Primed():
+		case <-ctx.Done():
+			t.close()
+			return
+		}
+		if err := t.stream.Err(); err != nil && err != io.EOF {
+			t.close()
+			b.log.Warn("previous track fetch failed", "song", req.song.ID, "err", err)
+			return
+		}
+		req.audio = t
+		b.log.Debug("fetched previous track", "song", req.song.ID, "took", time.Since(start))
+		select {
+		case b.playNowCh <- req:
+		case <-ctx.Done():
+			req.audio.close()
+		}
+	}()
+}
@@ -0,0 +1,85 @@
+package manager
+
+import (
+	"fmt"
+	"time"
+)
+
+// seekTimeout bounds how long Seek waits for the loop, which can be busy
+// waiting on a slow fetch at a rotation.
+const seekTimeout = 2 * time.Second
+
+type seekMsg struct {
+	Type     string        `json:"type"` // "seek"
+	ID       string        `json:"id"`
+	Position time.Duration `json:"position"`
+	Duration time.Duration `json:"duration"`
+}
+
+// seekReq asks the loop to move within the current track.
+type seekReq struct {
+	to       time.Duration
+	relative bool
+	done     chan seekResult
+}
+
+type seekResult struct {
+	pos time.Duration
+	err error
+}
+
+// Seek jumps to pos in the current track and returns where playback landed.
+func (b *Broadcaster) Seek(pos time.Duration) (time.Duration, error) {
+	return b.seek(seekReq{to: pos})
+}
+
+// SeekBy moves delta forward (or backward if negative) in the current track.
+func (b *Broadcaster) SeekBy(delta time.Duration) (time.Duration, error) {
+	return b.seek(seekReq{to: delta, relative: true})
+}
+
+func (b *Broadcaster) seek(req seekReq) (time.Duration, error) {
+	req.done = make(chan seekResult, 1)
+	select {
+	case b.seekCh <- req:
+	case <-time.After(seekTimeout):
+		return 0, fmt.Errorf("the broadcaster is busy; try again")
+	}
+	select {
+	case res := <-req.done:
+		return res.pos, res.err
+	case <-time.After(seekTimeout):
+		return 0, fmt.Errorf("the broadcaster is busy; try again")
+	}
+}
+
+// applySeek works out the new chunk index for req within t, moves t there
+// and tells clients. It runs on the loop goroutine.
+func (b *Broadcaster) applySeek(req seekReq, t *track) {
+	if t == nil || t.chunks == 0 {
+		req.done <- seekResult{err: fmt.Errorf("nothing is playing")}
+		return
+	}
+	from := t.pos
+	target := int(req.to / b.interval)
+	if req.relative {
+		target += from
+	}
+	if target < 0 {
+		target = 0
+	}
+	if target > t.chunks-1 {
+		target = t.chunks - 1 // the last chunk still plays, then the track rotates as usual
+	}
+	t.seek(target)
+	pos := time.Duration(target) * b.interval
+
+	b.mu.Lock()
+	b.chunkIdx = target
+	song := b.currentSong
+	b.mu.Unlock()
+
+	b.log.Info("seek", "song", song.ID, "from", time.Duration(from)*b.interval, "to", pos)
+	b.broadcastJSON(seekMsg{Type: "seek", ID: song.ID, Position: pos, Duration: time.Duration(t.chunks) * b.interval})
+	req.done <- seekResult{pos: pos}
+}
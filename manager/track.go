@@ -0,0 +1,128 @@
+package manager
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"time"
+
+	"github.com/Coop25/CC-Radio/accessor"
+	"github.com/Coop25/CC-Radio/chunker"
+)
+
+// track is a song or clip being streamed for the loop: its audio is read
+// ahead into a bounded buffer and handed out a chunk per tick.
+type track struct {
+	song   accessor.Song
+	open   chunker.Opener
+	stream *chunker.Stream
+	size   int // bytes per chunk
+	ahead  int // chunks buffered ahead of playback
+	pos    int // chunks played so far
+	chunks int // expected length; exact once the stream ends
+}
+
+// openTrack starts streaming song from open, length long.
+func (b *Broadcaster) openTrack(song accessor.Song, open chunker.Opener, length time.Duration) *track {
+	t := &track{
+		song:   song,
+		open:   open,
+		size:   chunker.Size(b.interval),
+		ahead:  int(b.buffer / b.interval),
+		chunks: int((length + b.interval - 1) / b.interval),
+	}
+	t.stream = chunker.NewStream(open, 0, t.size, t.ahead)
+	return t
+}
+
+// songOpener opens song's audio from the broadcaster's source, off bytes in.
+func (b *Broadcaster) songOpener(song accessor.Song) chunker.Opener {
+	return func(off int64) (io.ReadCloser, error) {
+		return b.audio.Open(song, off)
+	}
+}
+
+// bytesOpener serves audio already in memory.
+func bytesOpener(data []byte) chunker.Opener {
+	return func(off int64) (io.ReadCloser, error) {
+		if off > int64(len(data)) {
+			off = int64(len(data))
+		}
+		return io.NopCloser(bytes.NewReader(data[off:])), nil
+	}
+}
+
+// primeTrack streams song until its buffer is full. While the converter is
+// down it keeps trying, backing off in between; any other failure gives up
+// on the song, since the fetcher has already retried it.
+func (b *Broadcaster) primeTrack(ctx context.Context, song accessor.Song) (*track, error) {
+	for attempt := 0; ; attempt++ {
+		t := b.openTrack(song, b.songOpener(song), song.Duration)
+		select {
+		case <-t.stream.Primed():
+		case <-ctx.Done():
+			t.close()
+			return nil, ctx.Err()
+		}
+		err := t.stream.Err()
+		if err == nil || err == io.EOF {
+			return t, nil
+		}
+		t.close()
+		if !errors.Is(err, accessor.ErrConverterDown) {
+			return nil, err
+		}
+		d := accessor.Backoff(attempt, b.retryBase, b.retryMax)
+		b.errLimit.Warn(b.log, "prefetch:"+song.ID, "converter down; waiting to prefetch", "song", song.ID, "in", d)
+		select {
+		case <-time.After(d):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// next returns the next chunk; see chunker.Stream.Next.
+func (t *track) next() ([]byte, error) {
+	chunk, err := t.stream.Next()
+	if chunk != nil {
+		t.pos++
+	}
+	if err != nil {
+		t.chunks = t.pos
+	}
+	return chunk, err
+}
+
+// finished reports whether every chunk has been played.
+func (t *track) finished() bool {
+	return t.stream.Drained()
+}
+
+// seek moves playback to chunk target, skipping through what is buffered
+// when it can and restarting the stream there otherwise.
+func (t *track) seek(target int) {
+	if target == t.pos {
+		return
+	}
+	if target < t.pos || !t.stream.Skip(target-t.pos) {
+		t.stream.Close()
+		t.stream = chunker.NewStream(t.open, int64(target)*int64(t.size), t.size, t.ahead)
+	}
+	t.pos = target
+}
+
+// at is the playback position in chunks; zero when nothing is playing.
+func (t *track) at() int {
+	if t == nil {
+		return 0
+	}
+	return t.pos
+}
+
+func (t *track) close() {
+	if t != nil {
+		t.stream.Close()
+	}
+}
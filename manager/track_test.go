@@ -0,0 +1,125 @@
+package manager
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Coop25/CC-Radio/accessor"
+)
+
+// testAudio is an AudioSource over data. The first stream it opens part
+// way into a song, short of failAt, breaks off there as a dropped
+// connection would.
+type testAudio struct {
+	data   []byte
+	failAt int64 // 0 never fails
+
+	mu    sync.Mutex
+	opens []int64 // offset of each Open, in order
+}
+
+func (a *testAudio) Open(_ accessor.Song, off int64) (io.ReadCloser, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.opens = append(a.opens, off)
+	if off > 0 && a.failAt > off {
+		end := a.failAt
+		a.failAt = 0
+		return io.NopCloser(io.MultiReader(bytes.NewReader(a.data[off:end]), errReader{})), nil
+	}
+	return io.NopCloser(bytes.NewReader(a.data[off:])), nil
+}
+
+func (a *testAudio) openOffsets() []int64 {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return append([]int64(nil), a.opens...)
+}
+
+type errReader struct{}
+
+func (errReader) Read([]byte) (int, error) { return 0, errors.New("connection reset") }
+
+// playFrom reads t to the end, returning the audio it played.
+func playFrom(t *testing.T, tr *track) []byte {
+	t.Helper()
+	var out []byte
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		chunk, err := tr.next()
+		if err == io.EOF {
+			return out
+		}
+		if err != nil {
+			t.Fatalf("track failed at chunk %d: %v", tr.at(), err)
+		}
+		if chunk == nil {
+			if time.Now().After(deadline) {
+				t.Fatal("track stalled")
+			}
+			time.Sleep(time.Millisecond)
+			continue
+		}
+		out = append(out, chunk...)
+	}
+}
+
+func TestSeekReopensAtOffset(t *testing.T) {
+	const size = 600 // bytes per 100ms chunk
+	data := make([]byte, 20*size)
+	for i := range data {
+		data[i] = byte(i / size)
+	}
+	// 2s of 100ms chunks, two buffered ahead
+	openTrack := func(audio *testAudio) *track {
+		b := &Broadcaster{interval: 100 * time.Millisecond, buffer: 200 * time.Millisecond, audio: audio}
+		song := accessor.Song{ID: "s"}
+		return b.openTrack(song, b.songOpener(song), 2*time.Second)
+	}
+
+	t.Run("forward past the buffer", func(t *testing.T) {
+		audio := &testAudio{data: data}
+		tr := openTrack(audio)
+		defer tr.close()
+		<-tr.stream.Primed()
+
+		tr.seek(12)
+		if got := playFrom(t, tr); !bytes.Equal(got, data[12*size:]) {
+			t.Errorf("after seeking to chunk 12 played %d bytes, want %d", len(got), len(data[12*size:]))
+		}
+		if opens, want := audio.openOffsets(), []int64{0, 12 * size}; !slices.Equal(opens, want) {
+			t.Errorf("opened at %v, want %v", opens, want)
+		}
+	})
+
+	t.Run("back after a dropped connection", func(t *testing.T) {
+		// the reopen for the seek breaks off mid-chunk, so the stream
+		// resumes from there rather than from the seek target
+		audio := &testAudio{data: data, failAt: 15*size + 100}
+		tr := openTrack(audio)
+		defer tr.close()
+		tr.seek(0) // no-op at the start
+		for tr.at() < 14 {
+			chunk, err := tr.next()
+			if err != nil {
+				t.Fatalf("track failed at chunk %d: %v", tr.at(), err)
+			}
+			if chunk == nil {
+				time.Sleep(time.Millisecond)
+			}
+		}
+
+		tr.seek(10)
+		if got := playFrom(t, tr); !bytes.Equal(got, data[10*size:]) {
+			t.Errorf("after seeking back to chunk 10 played %d bytes, want %d", len(got), len(data[10*size:]))
+		}
+		if opens, want := audio.openOffsets(), []int64{0, 10 * size, 15*size + 100}; !slices.Equal(opens, want) {
+			t.Errorf("opened at %v, want %v", opens, want)
+		}
+	})
+}
@@ -0,0 +1,119 @@
+package manager
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/gorilla/websocket"
+)
+
+type voteMsg struct {
+	Type string `json:"type"`
+	VoteTally
+}
+
+// VoteTally is the state of the skip vote for the current song.
+type VoteTally struct {
+	SongID   string `json:"song_id"`
+	SongName string `json:"song_name"`
+	Votes    int    `json:"votes"`
+	Needed   int    `json:"needed"`
+	Skipped  bool   `json:"skipped"` // this vote crossed the threshold
+	Counted  bool   `json:"counted"` // false if the voter had already voted
+}
+
+// VoteSkip records a vote from voter (e.g. "discord:<user>" or "mc:<computer>")
+// against the current song and skips it once the threshold is reached.
+// Votes only count for the song they were cast on: a non-empty songID is
+// the song the voter saw, and the vote is refused once it has moved on.
+func (b *Broadcaster) VoteSkip(voter, songID string) (VoteTally, error) {
+	if voter == "" {
+		return VoteTally{}, fmt.Errorf("unknown voter")
+	}
+	b.mu.Lock()
+	if b.currentSong.ID == "" {
+		b.mu.Unlock()
+		return VoteTally{}, fmt.Errorf("nothing is playing")
+	}
+	if songID != "" && songID != b.currentSong.ID {
+		b.mu.Unlock()
+		return VoteTally{}, fmt.Errorf("that song is no longer playing")
+	}
+	_, dup := b.votes[voter]
+	if !dup {
+		b.votes[voter] = struct{}{}
+	}
+	t := b.tallyLocked()
+	t.Counted = !dup
+	if t.Votes >= t.Needed {
+		t.Skipped = true
+		b.votes = make(map[string]struct{})
+	}
+	b.mu.Unlock()
+
+	b.broadcastJSON(voteMsg{Type: "voteSkip", VoteTally: t})
+	if t.Skipped {
+		b.log.Info("vote to skip passed", "song", t.SongID, "votes", t.Votes, "needed", t.Needed)
+		b.Skip()
+	}
+	return t, nil
+}
+
+// VoteStatus returns the current tally without voting.
+func (b *Broadcaster) VoteStatus() VoteTally {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.tallyLocked()
+}
+
+// tallyLocked computes the tally; caller holds b.mu.
+// The threshold is a share of connected listeners, never less than voteMin (or one).
+func (b *Broadcaster) tallyLocked() VoteTally {
+	needed := int(math.Ceil(float64(len(b.conns)) * b.votePercent / 100))
+	if needed < b.voteMin {
+		needed = b.voteMin
+	}
+	if needed < 1 {
+		needed = 1
+	}
+	return VoteTally{
+		SongID:   b.currentSong.ID,
+		SongName: b.currentSong.Name,
+		Votes:    len(b.votes),
+		Needed:   needed,
+	}
+}
+
+// InGameVoting reports whether WebSocket clients may vote.
+func (b *Broadcaster) InGameVoting() bool {
+	return b.voteInGame
+}
+
+// ListenerVoterID identifies a WebSocket client as a voter: its computer ID
+// if it said hello, otherwise its connection. It is empty for a connection
+// that has gone, which VoteSkip refuses.
+func (b *Broadcaster) ListenerVoterID(conn *websocket.Conn) string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	l, ok := b.conns[conn]
+	if !ok {
+		return ""
+	}
+	if l.identified {
+		return fmt.Sprintf("mc:%s:%d", l.world, l.computerID)
+	}
+	return fmt.Sprintf("conn:%d", l.id)
+}
+
+// ListenerRaterID identifies a WebSocket client for ratings, which are saved
+// and outlive the connection: its computer ID once it has said hello, and
+// empty before, so a client can't rate again on every reconnect.
+func (b *Broadcaster) ListenerRaterID(conn *websocket.Conn) string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	l, ok := b.conns[conn]
+	if !ok || !l.identified {
+		return ""
+	}
+	return fmt.Sprintf("mc:%s:%d", l.world, l.computerID)
+}
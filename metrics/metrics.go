@@ -0,0 +1,322 @@
+// Package metrics is a minimal Prometheus-compatible registry.
+// It renders the text exposition format directly so scrape output can be
+// checked without a Prometheus server.
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// collector is anything that can write its own exposition lines.
+type collector interface {
+	name() string
+	write(w io.Writer)
+}
+
+// Registry holds a set of named metrics.
+type Registry struct {
+	mu         sync.Mutex
+	collectors map[string]collector
+}
+
+// Default is the registry the package-level constructors register into.
+var Default = NewRegistry()
+
+func NewRegistry() *Registry {
+	return &Registry{collectors: make(map[string]collector)}
+}
+
+func (r *Registry) register(c collector) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, dup := r.collectors[c.name()]; dup {
+		panic("metrics: duplicate metric " + c.name())
+	}
+	r.collectors[c.name()] = c
+}
+
+// WriteText renders every metric in the text exposition format, sorted by name.
+func (r *Registry) WriteText(w io.Writer) {
+	r.mu.Lock()
+	names := make([]string, 0, len(r.collectors))
+	for n := range r.collectors {
+		names = append(names, n)
+	}
+	cs := make([]collector, 0, len(names))
+	sort.Strings(names)
+	for _, n := range names {
+		cs = append(cs, r.collectors[n])
+	}
+	r.mu.Unlock()
+
+	for _, c := range cs {
+		c.write(w)
+	}
+}
+
+// Handler serves the registry for Prometheus to scrape.
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		r.WriteText(w)
+	})
+}
+
+// ---- counter / gauge ----
+
+type value struct {
+	mu sync.Mutex
+	v  float64
+}
+
+func (v *value) add(d float64) {
+	v.mu.Lock()
+	v.v += d
+	v.mu.Unlock()
+}
+
+func (v *value) set(x float64) {
+	v.mu.Lock()
+	v.v = x
+	v.mu.Unlock()
+}
+
+func (v *value) get() float64 {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return v.v
+}
+
+// Counter is a monotonically increasing value.
+type Counter struct {
+	n, help string
+	value
+}
+
+func NewCounter(name, help string) *Counter {
+	c := &Counter{n: name, help: help}
+	Default.register(c)
+	return c
+}
+
+func (c *Counter) Inc()           { c.add(1) }
+func (c *Counter) Add(d float64)  { c.add(d) }
+func (c *Counter) Value() float64 { return c.get() }
+func (c *Counter) name() string   { return c.n }
+func (c *Counter) write(w io.Writer) {
+	writeHeader(w, c.n, c.help, "counter")
+	fmt.Fprintf(w, "%s %s\n", c.n, formatFloat(c.get()))
+}
+
+// Gauge is a value that can go up and down.
+type Gauge struct {
+	n, help string
+	value
+}
+
+func NewGauge(name, help string) *Gauge {
+	g := &Gauge{n: name, help: help}
+	Default.register(g)
+	return g
+}
+
+func (g *Gauge) Set(x float64)  { g.set(x) }
+func (g *Gauge) Add(d float64)  { g.add(d) }
+func (g *Gauge) Value() float64 { return g.get() }
+func (g *Gauge) name() string   { return g.n }
+func (g *Gauge) write(w io.Writer) {
+	writeHeader(w, g.n, g.help, "gauge")
+	fmt.Fprintf(w, "%s %s\n", g.n, formatFloat(g.get()))
+}
+
+// CounterVec is a family of counters partitioned by one label.
+type CounterVec struct {
+	n, help, label string
+	mu             sync.Mutex
+	children       map[string]*value
+}
+
+func NewCounterVec(name, help, label string) *CounterVec {
+	c := &CounterVec{n: name, help: help, label: label, children: make(map[string]*value)}
+	Default.register(c)
+	return c
+}
+
+// Inc bumps the counter for the given label value.
+func (c *CounterVec) Inc(lv string) { c.child(lv).add(1) }
+
+// Value returns the current count for the given label value, without
+// creating a child for a value that was never counted.
+func (c *CounterVec) Value(lv string) float64 {
+	c.mu.Lock()
+	v, ok := c.children[lv]
+	c.mu.Unlock()
+	if !ok {
+		return 0
+	}
+	return v.get()
+}
+
+func (c *CounterVec) child(lv string) *value {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	v, ok := c.children[lv]
+	if !ok {
+		v = &value{}
+		c.children[lv] = v
+	}
+	return v
+}
+
+func (c *CounterVec) name() string { return c.n }
+func (c *CounterVec) write(w io.Writer) {
+	writeHeader(w, c.n, c.help, "counter")
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, lv := range sortedKeys(c.children) {
+		fmt.Fprintf(w, "%s{%s=%s} %s\n", c.n, c.label, quoteLabel(lv), formatFloat(c.children[lv].get()))
+	}
+}
+
+// ---- histogram ----
+
+// DefBuckets are latency buckets in seconds, suited to HTTP fetches.
+var DefBuckets = []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60}
+
+type histogram struct {
+	mu      sync.Mutex
+	buckets []float64
+	counts  []uint64
+	sum     float64
+	count   uint64
+}
+
+func newHistogram(buckets []float64) *histogram {
+	return &histogram{buckets: buckets, counts: make([]uint64, len(buckets))}
+}
+
+func (h *histogram) observe(x float64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for i, ub := range h.buckets {
+		if x <= ub {
+			h.counts[i]++
+		}
+	}
+	h.sum += x
+	h.count++
+}
+
+func (h *histogram) write(w io.Writer, name, labels string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	sep := ""
+	if labels != "" {
+		sep = ","
+	}
+	for i, ub := range h.buckets {
+		fmt.Fprintf(w, "%s_bucket{%s%sle=%s} %d\n", name, labels, sep, quoteLabel(formatFloat(ub)), h.counts[i])
+	}
+	fmt.Fprintf(w, "%s_bucket{%s%sle=\"+Inf\"} %d\n", name, labels, sep, h.count)
+	if labels != "" {
+		labels = "{" + labels + "}"
+	}
+	fmt.Fprintf(w, "%s_sum%s %s\n", name, labels, formatFloat(h.sum))
+	fmt.Fprintf(w, "%s_count%s %d\n", name, labels, h.count)
+}
+
+// Histogram counts observations into cumulative buckets.
+type Histogram struct {
+	n, help string
+	h       *histogram
+}
+
+func NewHistogram(name, help string, buckets []float64) *Histogram {
+	h := &Histogram{n: name, help: help, h: newHistogram(buckets)}
+	Default.register(h)
+	return h
+}
+
+func (h *Histogram) Observe(x float64) { h.h.observe(x) }
+func (h *Histogram) name() string      { return h.n }
+func (h *Histogram) write(w io.Writer) {
+	writeHeader(w, h.n, h.help, "histogram")
+	h.h.write(w, h.n, "")
+}
+
+// HistogramVec is a family of histograms partitioned by one label.
+type HistogramVec struct {
+	n, help, label string
+	buckets        []float64
+	mu             sync.Mutex
+	children       map[string]*histogram
+}
+
+func NewHistogramVec(name, help, label string, buckets []float64) *HistogramVec {
+	h := &HistogramVec{n: name, help: help, label: label, buckets: buckets, children: make(map[string]*histogram)}
+	Default.register(h)
+	return h
+}
+
+// Observe records x for the given label value.
+func (h *HistogramVec) Observe(lv string, x float64) {
+	h.mu.Lock()
+	c, ok := h.children[lv]
+	if !ok {
+		c = newHistogram(h.buckets)
+		h.children[lv] = c
+	}
+	h.mu.Unlock()
+	c.observe(x)
+}
+
+func (h *HistogramVec) name() string { return h.n }
+func (h *HistogramVec) write(w io.Writer) {
+	writeHeader(w, h.n, h.help, "histogram")
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, lv := range sortedKeys(h.children) {
+		h.children[lv].write(w, h.n, h.label+"="+quoteLabel(lv))
+	}
+}
+
+// ---- helpers ----
+
+func writeHeader(w io.Writer, name, help, typ string) {
+	help = strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(help)
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, typ)
+}
+
+// labelEscaper applies the exposition format's label value escaping, which
+// only knows backslash, double quote and newline (unlike Go's %q).
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func quoteLabel(v string) string {
+	return `"` + labelEscaper.Replace(v) + `"`
+}
+
+func formatFloat(f float64) string {
+	switch {
+	case math.IsInf(f, 1):
+		return "+Inf"
+	case math.IsInf(f, -1):
+		return "-Inf"
+	}
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,73 @@
+package metrics
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestWriteText(t *testing.T) {
+	r := NewRegistry()
+	c := &Counter{n: "test_requests_total", help: "Requests served."}
+	cv := &CounterVec{n: "test_errors_total", help: "Errors by kind.", label: "kind", children: make(map[string]*value)}
+	g := &Gauge{n: "test_listeners", help: "Connected\nclients."}
+	h := &Histogram{n: "test_latency_seconds", help: `Latency in \s.`, h: newHistogram([]float64{0.1, 1})}
+	hv := &HistogramVec{n: "test_fetch_seconds", help: "Fetch latency.", label: "op", buckets: []float64{1}, children: make(map[string]*histogram)}
+	for _, col := range []collector{c, cv, g, h, hv} {
+		r.register(col)
+	}
+
+	c.Inc()
+	c.Add(2)
+	cv.Inc("timeout")
+	cv.Inc(`say "hi"\now` + "\n")
+	g.Set(4)
+	g.Add(-1.5)
+	h.Observe(0.05)
+	h.Observe(0.5)
+	h.Observe(3)
+	hv.Observe(`a"b`, 0.5)
+
+	var b strings.Builder
+	r.WriteText(&b)
+	want := `# HELP test_errors_total Errors by kind.
+# TYPE test_errors_total counter
+test_errors_total{kind="say \"hi\"\\now\n"} 1
+test_errors_total{kind="timeout"} 1
+# HELP test_fetch_seconds Fetch latency.
+# TYPE test_fetch_seconds histogram
+test_fetch_seconds_bucket{op="a\"b",le="1"} 1
+test_fetch_seconds_bucket{op="a\"b",le="+Inf"} 1
+test_fetch_seconds_sum{op="a\"b"} 0.5
+test_fetch_seconds_count{op="a\"b"} 1
+# HELP test_latency_seconds Latency in \\s.
+# TYPE test_latency_seconds histogram
+test_latency_seconds_bucket{le="0.1"} 1
+test_latency_seconds_bucket{le="1"} 2
+test_latency_seconds_bucket{le="+Inf"} 3
+test_latency_seconds_sum 3.55
+test_latency_seconds_count 3
+# HELP test_listeners Connected\nclients.
+# TYPE test_listeners gauge
+test_listeners 2.5
+# HELP test_requests_total Requests served.
+# TYPE test_requests_total counter
+test_requests_total 3
+`
+	if got := b.String(); got != want {
+		t.Errorf("WriteText:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestCounterVecValueDoesNotCreate(t *testing.T) {
+	cv := &CounterVec{n: "test_total", label: "op", children: make(map[string]*value)}
+	if v := cv.Value("missing"); v != 0 {
+		t.Errorf("Value(missing) = %v, want 0", v)
+	}
+	cv.Inc("seen")
+	if v := cv.Value("seen"); v != 1 {
+		t.Errorf("Value(seen) = %v, want 1", v)
+	}
+	if len(cv.children) != 1 {
+		t.Errorf("children = %d, want 1 (reading must not add a series)", len(cv.children))
+	}
+}